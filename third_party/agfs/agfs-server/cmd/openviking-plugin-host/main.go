@@ -0,0 +1,404 @@
+// Command openviking-plugin-host loads a single external plugin shared
+// library and serves its filesystem.FileSystem/FileHandle operations over a
+// framed Unix-socket protocol to a parent pkg/plugin/manager.Manager. It is
+// forked once per loaded .so so that a crash inside the plugin (e.g. a
+// segfault) only takes down this child, not the embedder.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"plugin"
+	"sync"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+	agfsplugin "github.com/c4pt0r/agfs/agfs-server/pkg/plugin"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin/manager"
+)
+
+type server struct {
+	svc agfsplugin.ServicePlugin
+
+	mu        sync.Mutex
+	handles   map[int64]filesystem.FileHandle
+	handleGen int64
+}
+
+func main() {
+	libPath := flag.String("plugin", "", "path to the plugin shared library")
+	socketPath := flag.String("socket", "", "Unix socket to listen on")
+	flag.Parse()
+
+	if *libPath == "" || *socketPath == "" {
+		log.Fatal("openviking-plugin-host: -plugin and -socket are required")
+	}
+
+	svc, err := loadServicePlugin(*libPath)
+	if err != nil {
+		log.Fatalf("openviking-plugin-host: %v", err)
+	}
+
+	_ = os.Remove(*socketPath)
+	listener, err := net.Listen("unix", *socketPath)
+	if err != nil {
+		log.Fatalf("openviking-plugin-host: listen on %s: %v", *socketPath, err)
+	}
+	defer listener.Close()
+
+	s := &server{svc: svc, handles: make(map[int64]filesystem.FileHandle)}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			log.Printf("openviking-plugin-host: accept: %v", err)
+			return
+		}
+		go s.serve(conn)
+	}
+}
+
+// loadServicePlugin opens the shared library via Go's plugin package and
+// looks up its exported "NewPlugin" symbol, matching the contract that
+// mountablefs already requires of in-process plugins.
+func loadServicePlugin(libPath string) (agfsplugin.ServicePlugin, error) {
+	p, err := plugin.Open(libPath)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", libPath, err)
+	}
+	sym, err := p.Lookup("NewPlugin")
+	if err != nil {
+		return nil, fmt.Errorf("lookup NewPlugin in %s: %w", libPath, err)
+	}
+	factory, ok := sym.(func() agfsplugin.ServicePlugin)
+	if !ok {
+		return nil, fmt.Errorf("%s: NewPlugin has unexpected signature", libPath)
+	}
+	return factory(), nil
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		payload, err := manager.ReadFrame(conn)
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("openviking-plugin-host: read frame: %v", err)
+			}
+			return
+		}
+
+		var req manager.Request
+		if err := json.Unmarshal(payload, &req); err != nil {
+			log.Printf("openviking-plugin-host: decode request: %v", err)
+			return
+		}
+
+		resp := s.dispatch(req)
+		data, err := json.Marshal(resp)
+		if err != nil {
+			log.Printf("openviking-plugin-host: encode response: %v", err)
+			return
+		}
+		if err := manager.WriteFrame(conn, data); err != nil {
+			log.Printf("openviking-plugin-host: write frame: %v", err)
+			return
+		}
+	}
+}
+
+func (s *server) dispatch(req manager.Request) manager.Response {
+	result, err := s.call(req.Method, req.Args)
+	if err != nil {
+		return manager.Response{ID: req.ID, Error: err.Error()}
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return manager.Response{ID: req.ID, Error: err.Error()}
+	}
+	return manager.Response{ID: req.ID, Result: raw}
+}
+
+func (s *server) getHandle(id int64) (filesystem.FileHandle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.handles[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown handle %d", id)
+	}
+	return h, nil
+}
+
+func (s *server) storeHandle(h filesystem.FileHandle) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handleGen++
+	id := s.handleGen
+	s.handles[id] = h
+	return id
+}
+
+// call dispatches one RPC method by name to the loaded plugin or, for the
+// "Handle.*" methods, to a previously opened filesystem.FileHandle. The
+// method names mirror filesystem.FileSystem/FileHandle exactly so the
+// manager-side remotePlugin/remoteHandle need no translation layer.
+func (s *server) call(method string, rawArgs json.RawMessage) (interface{}, error) {
+	switch method {
+	case "Plugin.Name":
+		return s.svc.Name(), nil
+
+	case "ReadDir":
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return s.svc.ReadDir(args.Path)
+
+	case "Read":
+		var args struct {
+			Path   string `json:"path"`
+			Offset int64  `json:"offset"`
+			Size   int64  `json:"size"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return s.svc.Read(args.Path, args.Offset, args.Size)
+
+	case "Write":
+		var args struct {
+			Path   string               `json:"path"`
+			Data   []byte               `json:"data"`
+			Offset int64                `json:"offset"`
+			Flags  filesystem.WriteFlag `json:"flags"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return s.svc.Write(args.Path, args.Data, args.Offset, args.Flags)
+
+	case "Create":
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.svc.Create(args.Path)
+
+	case "Mkdir":
+		var args struct {
+			Path string `json:"path"`
+			Mode uint32 `json:"mode"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.svc.Mkdir(args.Path, args.Mode)
+
+	case "Remove":
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.svc.Remove(args.Path)
+
+	case "RemoveAll":
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.svc.RemoveAll(args.Path)
+
+	case "Stat":
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return s.svc.Stat(args.Path)
+
+	case "Rename":
+		var args struct {
+			OldPath string `json:"old_path"`
+			NewPath string `json:"new_path"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.svc.Rename(args.OldPath, args.NewPath)
+
+	case "Chmod":
+		var args struct {
+			Path string `json:"path"`
+			Mode uint32 `json:"mode"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.svc.Chmod(args.Path, args.Mode)
+
+	case "Touch":
+		var args struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		return nil, s.svc.Touch(args.Path)
+
+	case "Handle.Open":
+		var args struct {
+			Path  string              `json:"path"`
+			Flags filesystem.OpenFlag `json:"flags"`
+			Mode  uint32              `json:"mode"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.svc.OpenHandle(args.Path, args.Flags, args.Mode)
+		if err != nil {
+			return nil, err
+		}
+		return s.storeHandle(h), nil
+
+	case "Handle.Read":
+		var args struct {
+			HandleID int64 `json:"handle_id"`
+			Size     int   `json:"size"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.getHandle(args.HandleID)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, args.Size)
+		n, err := h.Read(buf)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return buf[:n], nil
+
+	case "Handle.ReadAt":
+		var args struct {
+			HandleID int64 `json:"handle_id"`
+			Size     int   `json:"size"`
+			Offset   int64 `json:"offset"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.getHandle(args.HandleID)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, args.Size)
+		n, err := h.ReadAt(buf, args.Offset)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return buf[:n], nil
+
+	case "Handle.Write":
+		var args struct {
+			HandleID int64  `json:"handle_id"`
+			Data     []byte `json:"data"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.getHandle(args.HandleID)
+		if err != nil {
+			return nil, err
+		}
+		return h.Write(args.Data)
+
+	case "Handle.WriteAt":
+		var args struct {
+			HandleID int64  `json:"handle_id"`
+			Data     []byte `json:"data"`
+			Offset   int64  `json:"offset"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.getHandle(args.HandleID)
+		if err != nil {
+			return nil, err
+		}
+		return h.WriteAt(args.Data, args.Offset)
+
+	case "Handle.Seek":
+		var args struct {
+			HandleID int64 `json:"handle_id"`
+			Offset   int64 `json:"offset"`
+			Whence   int   `json:"whence"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.getHandle(args.HandleID)
+		if err != nil {
+			return nil, err
+		}
+		return h.Seek(args.Offset, args.Whence)
+
+	case "Handle.Sync":
+		var args struct {
+			HandleID int64 `json:"handle_id"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.getHandle(args.HandleID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, h.Sync()
+
+	case "Handle.Stat":
+		var args struct {
+			HandleID int64 `json:"handle_id"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.getHandle(args.HandleID)
+		if err != nil {
+			return nil, err
+		}
+		return h.Stat()
+
+	case "Handle.Close":
+		var args struct {
+			HandleID int64 `json:"handle_id"`
+		}
+		if err := json.Unmarshal(rawArgs, &args); err != nil {
+			return nil, err
+		}
+		h, err := s.getHandle(args.HandleID)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		delete(s.handles, args.HandleID)
+		s.mu.Unlock()
+		return nil, h.Close()
+
+	default:
+		return nil, fmt.Errorf("unknown method %q", method)
+	}
+}
@@ -0,0 +1,52 @@
+// Command openviking-server builds the same mountablefs.MountableFS used by
+// the CGo bindings (cmd/pybinding) and serves it over gRPC, either on a TCP
+// address or a Unix socket, so remote agents and non-cgo languages can mount
+// an openviking instance like any other plugin.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"strings"
+
+	googlegrpc "google.golang.org/grpc"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/mountablefs"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin/api"
+	ovgrpc "github.com/c4pt0r/agfs/agfs-server/pkg/service/grpc"
+	openvikingpb "github.com/c4pt0r/agfs/agfs-server/pkg/service/grpc/openvikingpb"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugins/memfs"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugins/s3fs"
+)
+
+func main() {
+	addr := flag.String("addr", ":7812", `listen address; prefix with "unix://" for a Unix socket`)
+	flag.Parse()
+
+	fs := mountablefs.NewMountableFS(api.PoolConfig{MaxInstances: 10})
+	fs.RegisterPluginFactory("memfs", func() plugin.ServicePlugin { return memfs.NewMemFSPlugin() })
+	fs.RegisterPluginFactory("s3fs", func() plugin.ServicePlugin { return s3fs.NewS3FSPlugin() })
+
+	listener, err := listen(*addr)
+	if err != nil {
+		log.Fatalf("openviking-server: %v", err)
+	}
+
+	grpcServer := googlegrpc.NewServer()
+	openvikingpb.RegisterOpenvikingServiceServer(grpcServer, ovgrpc.NewServer(fs))
+
+	log.Printf("openviking-server: listening on %s", *addr)
+	if err := grpcServer.Serve(listener); err != nil {
+		log.Fatalf("openviking-server: serve: %v", err)
+	}
+}
+
+func listen(addr string) (net.Listener, error) {
+	if rest, ok := strings.CutPrefix(addr, "unix://"); ok {
+		return net.Listen("unix", rest)
+	}
+	return net.Listen("tcp", addr)
+}
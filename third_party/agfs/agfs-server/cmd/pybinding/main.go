@@ -4,21 +4,35 @@ package main
 #include <stdlib.h>
 #include <stdint.h>
 #include <string.h>
+
+// agfs_stream_callback receives one chunk of an AGFS_ReadStream transfer.
+// done is non-zero on the final call (data/size are 0/NULL then).
+typedef void (*agfs_stream_callback)(int64_t client_id, const char* data, int64_t size, int done);
+
+static inline void agfs_invoke_stream_callback(agfs_stream_callback cb, int64_t client_id, const char* data, int64_t size, int done) {
+    cb(client_id, data, size, done);
+}
 */
 import "C"
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 	"unsafe"
 
 	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem/export"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem/pagecache"
 	"github.com/c4pt0r/agfs/agfs-server/pkg/mountablefs"
 	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin"
 	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin/api"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin/configschema"
 	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin/loader"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin/manager"
 	"github.com/c4pt0r/agfs/agfs-server/pkg/plugins/gptfs"
 	"github.com/c4pt0r/agfs/agfs-server/pkg/plugins/heartbeatfs"
 	"github.com/c4pt0r/agfs/agfs-server/pkg/plugins/hellofs"
@@ -43,14 +57,48 @@ var (
 	errorBuffer   = make(map[int64]string)
 	errorBufferMu sync.RWMutex
 	errorIDGen    int64
+
+	// pluginManager supervises external plugin shared libraries in their own
+	// child processes so a crash inside a plugin can't take down this
+	// embedder. See pkg/plugin/manager.
+	pluginManager *manager.Manager
+
+	// pageCache write-back-caches AGFS_HandleWrite/Read for every handle
+	// opened via AGFS_OpenHandle, per poolConfig.PageCache. See
+	// pkg/filesystem/pagecache.
+	pageCache *pagecache.Cache
 )
 
 func init() {
 	poolConfig := api.PoolConfig{
 		MaxInstances: 10,
+		PageCache: pagecache.Config{
+			Enabled:   false,
+			ChunkSize: pagecache.DefaultChunkSize,
+			MemBudget: 256 << 20,
+			Workers:   4,
+		},
 	}
 	globalFS = mountablefs.NewMountableFS(poolConfig)
+	pageCache = pagecache.New(poolConfig.PageCache)
 	registerBuiltinPlugins()
+
+	m, err := manager.New("openviking-plugins.json", "openviking-plugin-sockets")
+	if err != nil {
+		panic(fmt.Sprintf("pybinding: init plugin manager: %v", err))
+	}
+	pluginManager = m
+	// Recover logs and skips any entry it can't re-fork (e.g. the host
+	// binary isn't resolvable) rather than erroring, so a stale registry
+	// entry can't turn into a permanent init-time panic.
+	_ = pluginManager.Recover(func(libraryPath string, mountPoints []string, config map[string]interface{}) error {
+		for _, mp := range mountPoints {
+			if err := globalFS.MountPlugin(libraryPath, mp, config); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func registerBuiltinPlugins() {
@@ -145,14 +193,48 @@ func AGFS_Health(clientID int64) C.int {
 
 //export AGFS_GetCapabilities
 func AGFS_GetCapabilities(clientID int64) *C.char {
+	builtin := []string{"handlefs", "grep", "digest", "stream", "touch"}
+
+	globalFSMu.RLock()
+	fs := globalFS
+	globalFSMu.RUnlock()
+
 	caps := map[string]interface{}{
 		"version":  "binding",
-		"features": []string{"handlefs", "grep", "digest", "stream", "touch"},
+		"features": unionCapabilities(builtin, fs),
 	}
 	data, _ := json.Marshal(caps)
 	return C.CString(string(data))
 }
 
+// unionCapabilities returns builtin plus every per-mount capability
+// declared by currently-mounted plugins that implement
+// configschema.VersionedPlugin (duplicates removed, order not significant).
+func unionCapabilities(builtin []string, fs *mountablefs.MountableFS) []string {
+	seen := make(map[string]bool, len(builtin))
+	out := make([]string, 0, len(builtin))
+	for _, c := range builtin {
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+
+	for _, m := range fs.GetMounts() {
+		vp, ok := m.Plugin.(configschema.VersionedPlugin)
+		if !ok {
+			continue
+		}
+		for _, c := range vp.SupportedCapabilities() {
+			if !seen[c] {
+				seen[c] = true
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
 //export AGFS_Ls
 func AGFS_Ls(clientID int64, path *C.char) *C.char {
 	p := C.GoString(path)
@@ -378,6 +460,29 @@ func AGFS_Mounts(clientID int64) *C.char {
 	return C.CString(string(data))
 }
 
+// negotiateMountConfig runs config through configschema.Negotiate against a
+// fresh instance of fsType, upgrading it to the plugin's newest declared
+// config version and checking any "required_capabilities" it lists. Plugins
+// that don't implement configschema.VersionedPlugin (most builtins still
+// don't) opt out silently, the same way unionCapabilities treats them:
+// config is returned unchanged and the mount proceeds as it always has.
+func negotiateMountConfig(fs *mountablefs.MountableFS, fsType string, config map[string]interface{}) (map[string]interface{}, error) {
+	inst, err := fs.NewPluginInstance(fsType)
+	if err != nil {
+		// Let the real mount attempt below surface the "unknown fstype"
+		// error; duplicating it here would just produce two slightly
+		// different messages for the same failure.
+		return config, nil
+	}
+
+	vp, ok := inst.(configschema.VersionedPlugin)
+	if !ok {
+		return config, nil
+	}
+
+	return configschema.Negotiate(vp, config)
+}
+
 //export AGFS_Mount
 func AGFS_Mount(clientID int64, fstype *C.char, path *C.char, configJSON *C.char) *C.char {
 	fsType := C.GoString(fstype)
@@ -393,12 +498,28 @@ func AGFS_Mount(clientID int64, fstype *C.char, path *C.char, configJSON *C.char
 	fs := globalFS
 	globalFSMu.Unlock()
 
-	err := fs.MountPlugin(fsType, p, config)
+	config, err := negotiateMountConfig(fs, fsType, config)
 	if err != nil {
+		var negErr *configschema.Error
+		if errors.As(err, &negErr) {
+			return C.CString(fmt.Sprintf(`{"error_id": %d, "error_code": %q}`, storeError(err), negErr.ID))
+		}
+		errorID := storeError(err)
+		return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
+	}
+
+	if err := fs.MountPlugin(fsType, p, config); err != nil {
 		errorID := storeError(err)
 		return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
 	}
 
+	// For plugins loaded through pluginManager, fsType is the library path
+	// (see the Recover remount callback in init()), so this persists the
+	// new mount point for recovery. It's a no-op for builtin fstypes that
+	// pluginManager never loaded.
+	mountPoints := append(append([]string{}, pluginManager.MountPoints(fsType)...), p)
+	_ = pluginManager.SetMountPoints(fsType, mountPoints, config)
+
 	return C.CString(fmt.Sprintf(`{"message": "mounted %s at %s"}`, fsType, p))
 }
 
@@ -427,10 +548,17 @@ func AGFS_LoadPlugin(clientID int64, libraryPath *C.char) *C.char {
 	fs := globalFS
 	globalFSMu.Unlock()
 
-	p, err := fs.LoadExternalPlugin(libPath)
+	// Prefer an out-of-process host: a crash inside the plugin then only
+	// kills its child, and the manager restarts it transparently on the
+	// next call. Fall back to the in-process loader for plugins that the
+	// host can't serve (e.g. ones relying on process-local globals).
+	p, err := pluginManager.Load(libPath)
 	if err != nil {
-		errorID := storeError(err)
-		return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
+		p, err = fs.LoadExternalPlugin(libPath)
+		if err != nil {
+			errorID := storeError(err)
+			return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
+		}
 	}
 
 	return C.CString(fmt.Sprintf(`{"message": "loaded plugin %s", "name": "%s"}`, libPath, p.Name()))
@@ -444,6 +572,10 @@ func AGFS_UnloadPlugin(clientID int64, libraryPath *C.char) *C.char {
 	fs := globalFS
 	globalFSMu.Unlock()
 
+	if err := pluginManager.Unload(libPath); err == nil {
+		return C.CString(`{"message": "unloaded plugin"}`)
+	}
+
 	err := fs.UnloadExternalPlugin(libPath)
 	if err != nil {
 		errorID := storeError(err)
@@ -478,7 +610,7 @@ func AGFS_OpenHandle(clientID int64, path *C.char, flags C.int, mode C.uint, lea
 		return -1
 	}
 
-	id := storeHandle(handle)
+	id := storeHandle(pageCache.Wrap(handle))
 	return C.int64_t(id)
 }
 
@@ -644,6 +776,87 @@ func AGFS_GetHandleInfo(handleID C.int64_t) *C.char {
 	return C.CString(string(data))
 }
 
+const readStreamChunkSize = 1 << 20 // 1 MiB, used when chunkSize <= 0
+
+//export AGFS_ReadStream
+func AGFS_ReadStream(clientID int64, path *C.char, chunkSize C.int64_t, callback C.agfs_stream_callback) *C.char {
+	p := C.GoString(path)
+	size := int64(chunkSize)
+	if size <= 0 {
+		size = readStreamChunkSize
+	}
+
+	globalFSMu.RLock()
+	fs := globalFS
+	globalFSMu.RUnlock()
+
+	handle, err := fs.OpenHandle(p, filesystem.OpenFlag(0), 0)
+	if err != nil {
+		errorID := storeError(err)
+		return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
+	}
+	defer handle.Close()
+
+	buf := make([]byte, size)
+	for {
+		n, err := handle.Read(buf)
+		if n > 0 {
+			C.agfs_invoke_stream_callback(callback, C.int64_t(clientID), (*C.char)(unsafe.Pointer(&buf[0])), C.int64_t(n), 0)
+		}
+		if err != nil {
+			break
+		}
+	}
+	C.agfs_invoke_stream_callback(callback, C.int64_t(clientID), nil, 0, 1)
+
+	return C.CString(`{"message": "stream complete"}`)
+}
+
+//export AGFS_Export
+func AGFS_Export(clientID int64, path *C.char, format *C.char, outFD C.int) *C.char {
+	p := C.GoString(path)
+	fmtStr := C.GoString(format)
+
+	globalFSMu.RLock()
+	fs := globalFS
+	globalFSMu.RUnlock()
+
+	out := os.NewFile(uintptr(outFD), "agfs-export")
+	if out == nil {
+		errorID := storeError(fmt.Errorf("invalid output file descriptor %d", int(outFD)))
+		return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
+	}
+
+	if err := export.Export(fs, p, export.Format(fmtStr), out); err != nil {
+		errorID := storeError(err)
+		return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
+	}
+
+	return C.CString(fmt.Sprintf(`{"message": "exported %s as %s"}`, p, fmtStr))
+}
+
+//export AGFS_Import
+func AGFS_Import(clientID int64, path *C.char, gzipped C.int, inFD C.int) *C.char {
+	p := C.GoString(path)
+
+	globalFSMu.RLock()
+	fs := globalFS
+	globalFSMu.RUnlock()
+
+	in := os.NewFile(uintptr(inFD), "agfs-import")
+	if in == nil {
+		errorID := storeError(fmt.Errorf("invalid input file descriptor %d", int(inFD)))
+		return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
+	}
+
+	if err := export.Import(fs, p, in, gzipped != 0); err != nil {
+		errorID := storeError(err)
+		return C.CString(fmt.Sprintf(`{"error_id": %d}`, errorID))
+	}
+
+	return C.CString(fmt.Sprintf(`{"message": "imported into %s"}`, p))
+}
+
 //export AGFS_GetPluginLoader
 func AGFS_GetPluginLoader() unsafe.Pointer {
 	globalFSMu.RLock()
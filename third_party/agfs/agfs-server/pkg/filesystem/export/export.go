@@ -0,0 +1,158 @@
+// Package export snapshots and restores whole subtrees of a mounted
+// plugin as a single archive stream, composing ReadDir+OpenHandle the same
+// way any other filesystem.FileSystem caller would rather than round
+// tripping every file through JSON. It backs AGFS_Export/AGFS_Import and
+// the "tar"/"tar+gzip" output modes of AGFS_ReadStream's sibling entry
+// points.
+package export
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+)
+
+// Format selects how Export lays out the walked tree on the wire.
+type Format string
+
+const (
+	// FormatRaw streams only the file at the given path verbatim; root
+	// must not be a directory.
+	FormatRaw Format = "raw"
+	// FormatTar walks root recursively into a tar stream.
+	FormatTar Format = "tar"
+	// FormatTarGzip is FormatTar piped through gzip.
+	FormatTarGzip Format = "tar+gzip"
+)
+
+// FS is the subset of filesystem.FileSystem that Export/Import need.
+type FS interface {
+	ReadDir(path string) ([]filesystem.FileInfo, error)
+	Stat(path string) (filesystem.FileInfo, error)
+	OpenHandle(path string, flags filesystem.OpenFlag, mode uint32) (filesystem.FileHandle, error)
+	Mkdir(path string, mode uint32) error
+	Create(path string) error
+}
+
+// Export walks root (or streams it directly, for FormatRaw) and writes the
+// result to w in the given format.
+func Export(fs FS, root string, format Format, w io.Writer) error {
+	switch format {
+	case FormatRaw:
+		return exportRaw(fs, root, w)
+	case FormatTar:
+		return exportTar(fs, root, w)
+	case FormatTarGzip:
+		gw := gzip.NewWriter(w)
+		if err := exportTar(fs, root, gw); err != nil {
+			gw.Close()
+			return err
+		}
+		return gw.Close()
+	default:
+		return fmt.Errorf("export: unknown format %q", format)
+	}
+}
+
+func exportRaw(fs FS, root string, w io.Writer) error {
+	h, err := fs.OpenHandle(root, filesystem.OpenFlag(0), 0) // flags 0 is read-only
+	if err != nil {
+		return fmt.Errorf("export: open %s: %w", root, err)
+	}
+	defer h.Close()
+
+	_, err = io.Copy(w, readerFunc(h.Read))
+	return err
+}
+
+func exportTar(fs FS, root string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	if err := walkIntoTar(fs, root, tw); err != nil {
+		tw.Close()
+		return err
+	}
+	return tw.Close()
+}
+
+func walkIntoTar(fs FS, root string, tw *tar.Writer) error {
+	info, err := fs.Stat(root)
+	if err != nil {
+		return fmt.Errorf("export: stat %s: %w", root, err)
+	}
+	return writeTarEntry(fs, root, root, info, tw)
+}
+
+func writeTarEntry(fs FS, root, p string, info filesystem.FileInfo, tw *tar.Writer) error {
+	hdr := &tar.Header{
+		Name:    relTarName(root, p, info.IsDir),
+		Mode:    int64(info.Mode),
+		Size:    info.Size,
+		ModTime: info.ModTime,
+	}
+	if info.IsDir {
+		hdr.Typeflag = tar.TypeDir
+		hdr.Size = 0
+	} else {
+		hdr.Typeflag = tar.TypeReg
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("export: write tar header for %s: %w", p, err)
+	}
+
+	if info.IsDir {
+		entries, err := fs.ReadDir(p)
+		if err != nil {
+			return fmt.Errorf("export: read dir %s: %w", p, err)
+		}
+		for _, e := range entries {
+			if err := writeTarEntry(fs, root, path.Join(p, e.Name), e, tw); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	h, err := fs.OpenHandle(p, filesystem.OpenFlag(0), 0) // flags 0 is read-only
+	if err != nil {
+		return fmt.Errorf("export: open %s: %w", p, err)
+	}
+	defer h.Close()
+
+	if _, err := io.CopyN(tw, readerFunc(h.Read), info.Size); err != nil && err != io.EOF {
+		return fmt.Errorf("export: copy %s: %w", p, err)
+	}
+	return nil
+}
+
+// relTarName builds a tar entry name for p relative to root, so exporting
+// e.g. /mnt/data with a.txt inside produces the entry "a.txt" rather than
+// "mnt/data/a.txt" (which Import could never correctly restore relative to
+// a different destRoot).
+func relTarName(root, p string, isDir bool) string {
+	name := strings.TrimPrefix(path.Clean(p), path.Clean(root))
+	name = trimLeadingSlash(name)
+	if name == "" {
+		name = "."
+	}
+	if isDir && name != "." {
+		name += "/"
+	}
+	return name
+}
+
+func trimLeadingSlash(p string) string {
+	for len(p) > 0 && p[0] == '/' {
+		p = p[1:]
+	}
+	return p
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
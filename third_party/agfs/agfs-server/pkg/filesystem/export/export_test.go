@@ -0,0 +1,227 @@
+package export
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"path"
+	"sort"
+	"testing"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+)
+
+// fakeFS is a minimal in-memory FS for exercising Export/Import round trips
+// without a real mounted plugin.
+type fakeFS struct {
+	dirs  map[string]bool
+	files map[string][]byte
+}
+
+func newFakeFS() *fakeFS {
+	return &fakeFS{dirs: map[string]bool{"/": true}, files: map[string][]byte{}}
+}
+
+func (f *fakeFS) mkdirAll(p string) {
+	p = path.Clean(p)
+	for p != "/" && p != "." && !f.dirs[p] {
+		f.dirs[p] = true
+		p = path.Dir(p)
+	}
+}
+
+func (f *fakeFS) putFile(p string, data []byte) {
+	f.mkdirAll(path.Dir(p))
+	f.files[path.Clean(p)] = data
+}
+
+func (f *fakeFS) ReadDir(p string) ([]filesystem.FileInfo, error) {
+	p = path.Clean(p)
+	seen := map[string]bool{}
+	var out []filesystem.FileInfo
+	add := func(full string, isDir bool, size int64) {
+		if path.Dir(full) != p {
+			return
+		}
+		name := path.Base(full)
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		out = append(out, filesystem.FileInfo{Name: name, Size: size, IsDir: isDir})
+	}
+	for d := range f.dirs {
+		add(d, true, 0)
+	}
+	for fp, data := range f.files {
+		add(fp, false, int64(len(data)))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (f *fakeFS) Stat(p string) (filesystem.FileInfo, error) {
+	p = path.Clean(p)
+	if f.dirs[p] {
+		return filesystem.FileInfo{Name: path.Base(p), IsDir: true}, nil
+	}
+	data, ok := f.files[p]
+	if !ok {
+		return filesystem.FileInfo{}, fakeNotExist(p)
+	}
+	return filesystem.FileInfo{Name: path.Base(p), Size: int64(len(data))}, nil
+}
+
+func (f *fakeFS) OpenHandle(p string, flags filesystem.OpenFlag, mode uint32) (filesystem.FileHandle, error) {
+	p = path.Clean(p)
+	if _, ok := f.files[p]; !ok {
+		f.putFile(p, nil)
+	}
+	return &fakeHandle{fs: f, path: p, flags: flags}, nil
+}
+
+func (f *fakeFS) Mkdir(p string, mode uint32) error {
+	f.mkdirAll(p)
+	return nil
+}
+
+func (f *fakeFS) Create(p string) error {
+	f.putFile(p, nil)
+	return nil
+}
+
+type fakeNotExist string
+
+func (e fakeNotExist) Error() string { return string(e) + ": no such file" }
+
+// fakeHandle implements filesystem.FileHandle against a fakeFS entry.
+type fakeHandle struct {
+	fs    *fakeFS
+	path  string
+	flags filesystem.OpenFlag
+	pos   int64
+}
+
+func (h *fakeHandle) ReadAt(p []byte, offset int64) (int, error) {
+	data := h.fs.files[h.path]
+	if offset >= int64(len(data)) {
+		return 0, nil
+	}
+	n := copy(p, data[offset:])
+	return n, nil
+}
+
+func (h *fakeHandle) Read(p []byte) (int, error) {
+	n, err := h.ReadAt(p, h.pos)
+	h.pos += int64(n)
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, err
+}
+
+func (h *fakeHandle) WriteAt(p []byte, offset int64) (int, error) {
+	data := h.fs.files[h.path]
+	end := offset + int64(len(p))
+	if end > int64(len(data)) {
+		grown := make([]byte, end)
+		copy(grown, data)
+		data = grown
+	}
+	n := copy(data[offset:], p)
+	h.fs.files[h.path] = data
+	return n, nil
+}
+
+func (h *fakeHandle) Write(p []byte) (int, error) {
+	n, err := h.WriteAt(p, h.pos)
+	h.pos += int64(n)
+	return n, err
+}
+
+func (h *fakeHandle) Seek(offset int64, whence int) (int64, error) {
+	h.pos = offset
+	return h.pos, nil
+}
+
+func (h *fakeHandle) Sync() error { return nil }
+func (h *fakeHandle) Stat() (filesystem.FileInfo, error) { return h.fs.Stat(h.path) }
+func (h *fakeHandle) Close() error                       { return nil }
+func (h *fakeHandle) Path() string                       { return h.path }
+func (h *fakeHandle) Flags() filesystem.OpenFlag         { return h.flags }
+
+func TestExportImportTarRoundTrip(t *testing.T) {
+	src := newFakeFS()
+	src.putFile("/mnt/data/a.txt", []byte("hello"))
+	src.putFile("/mnt/data/sub/b.txt", []byte("world"))
+
+	var buf bytes.Buffer
+	if err := Export(src, "/mnt/data", FormatTar, &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dst := newFakeFS()
+	if err := Import(dst, "/restore", &buf, false); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	for rel, want := range map[string]string{
+		"a.txt":     "hello",
+		"sub/b.txt": "world",
+	} {
+		got, ok := dst.files[path.Join("/restore", rel)]
+		if !ok {
+			t.Fatalf("Import: missing %s under /restore (tar entry names weren't made relative to the export root)", rel)
+		}
+		if string(got) != want {
+			t.Fatalf("Import: %s = %q, want %q", rel, got, want)
+		}
+	}
+
+	// The mount path itself must not leak into the restored layout.
+	if _, ok := dst.files[path.Join("/restore", "mnt/data/a.txt")]; ok {
+		t.Fatal("Import: restored under the absolute source path instead of being relative to destRoot")
+	}
+}
+
+func TestImportRejectsTarEntryEscapingDestRoot(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "../../etc/passwd", Typeflag: tar.TypeReg, Size: 4}); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	if _, err := tw.Write([]byte("evil")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	dst := newFakeFS()
+	if err := Import(dst, "/restore", &buf, false); err == nil {
+		t.Fatal("Import: want an error for a tar entry escaping destRoot, got nil")
+	}
+	if _, ok := dst.files["/etc/passwd"]; ok {
+		t.Fatal("Import: wrote outside destRoot despite returning an error")
+	}
+}
+
+func TestExportImportTarGzipRoundTrip(t *testing.T) {
+	src := newFakeFS()
+	src.putFile("/data/a.txt", []byte("gzip me"))
+
+	var buf bytes.Buffer
+	if err := Export(src, "/data", FormatTarGzip, &buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dst := newFakeFS()
+	if err := Import(dst, "/restore", &buf, true); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	got, ok := dst.files[path.Join("/restore", "a.txt")]
+	if !ok || string(got) != "gzip me" {
+		t.Fatalf("Import: a.txt = %q, ok=%v, want %q", got, ok, "gzip me")
+	}
+}
@@ -0,0 +1,87 @@
+package export
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+)
+
+// Import unpacks a tar stream written by Export (gzip-compressed when
+// gzipped is true; the stream itself is not inspected) into destRoot on fs,
+// recreating directories and files via Mkdir/Create/Write.
+func Import(fs FS, destRoot string, r io.Reader, gzipped bool) error {
+	if gzipped {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("export: open gzip stream: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	cleanRoot := path.Clean(destRoot)
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("export: read tar header: %w", err)
+		}
+
+		// hdr.Name comes straight off an externally-supplied stream (e.g.
+		// AGFS_Import's inFD), not only archives this package produced;
+		// path.Join already cleans the result (and keeps an absolute name
+		// under destRoot), so only ".." elements climbing above destRoot
+		// can land it outside cleanRoot.
+		target := path.Join(destRoot, hdr.Name)
+		if target != cleanRoot && !strings.HasPrefix(target, cleanRoot+"/") {
+			return fmt.Errorf("export: tar entry %q escapes destRoot %s", hdr.Name, destRoot)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := fs.Mkdir(target, uint32(hdr.Mode)); err != nil {
+				return fmt.Errorf("export: mkdir %s: %w", target, err)
+			}
+		case tar.TypeReg:
+			if err := importFile(fs, target, tr); err != nil {
+				return err
+			}
+		default:
+			// Symlinks, devices, etc. aren't part of the AGFS filesystem
+			// model; skip them rather than fail the whole import.
+		}
+	}
+}
+
+func importFile(fs FS, target string, r io.Reader) error {
+	if err := fs.Create(target); err != nil {
+		return fmt.Errorf("export: create %s: %w", target, err)
+	}
+
+	// flags 0 is read-only (see export.go's identical-looking OpenHandle
+	// calls); restoring a file needs write access, and truncate since
+	// Create may have left a stale file of a different size in place.
+	h, err := fs.OpenHandle(target, filesystem.OpenFlagWrite|filesystem.OpenFlagTruncate, 0)
+	if err != nil {
+		return fmt.Errorf("export: open %s for write: %w", target, err)
+	}
+	defer h.Close()
+
+	if _, err := io.Copy(writerFunc(h.Write), r); err != nil {
+		return fmt.Errorf("export: write %s: %w", target, err)
+	}
+	return nil
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
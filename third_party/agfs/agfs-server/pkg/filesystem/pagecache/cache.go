@@ -0,0 +1,340 @@
+// Package pagecache adds an opt-in write-back cache in front of a
+// filesystem.FileHandle: writes land in memory (or, once a memory budget is
+// exceeded, in mmapped swap files) and are flushed asynchronously to the
+// wrapped handle by an UploadPipeline, while reads merge any still-dirty
+// chunks over the underlying data.
+package pagecache
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+)
+
+// DefaultChunkSize matches the window most plugins already buffer reads in.
+const DefaultChunkSize = 4 << 20 // 4 MiB
+
+// Config controls a pagecache-wrapped mount. It is threaded through from
+// api.PoolConfig so a mount can opt in (or not) per plugin.
+type Config struct {
+	// Enabled turns the write-back cache on for a mount. When false,
+	// Wrap returns the handle unchanged.
+	Enabled bool
+	// ChunkSize is the fixed size of each cached window, in bytes.
+	// Defaults to DefaultChunkSize.
+	ChunkSize int64
+	// MemBudget is the maximum number of bytes held in MemChunks across
+	// every handle of the Cache, each MemChunk counting its full ChunkSize;
+	// past it, chunks are spilled to SwapDir. Zero or less means no limit.
+	MemBudget int64
+	// Workers bounds how many chunk flushes may be in flight at once,
+	// shared across every handle wrapped with this Config.
+	Workers int
+	// SwapDir is where SwapFileChunks are created. Defaults to os.TempDir().
+	SwapDir string
+}
+
+func (c Config) withDefaults() Config {
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = DefaultChunkSize
+	}
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.SwapDir == "" {
+		c.SwapDir = os.TempDir()
+	}
+	return c
+}
+
+// Cache is a shared write-back cache for one mount: it owns the worker pool
+// and memory-budget accounting that every CachedHandle opened against the
+// mount draws from.
+type Cache struct {
+	cfg  Config
+	pool *workerPool
+
+	mu        sync.Mutex
+	dirtyBytes int64
+}
+
+// New builds a Cache from cfg, filling in defaults for any zero fields.
+func New(cfg Config) *Cache {
+	cfg = cfg.withDefaults()
+	return &Cache{cfg: cfg, pool: newWorkerPool(cfg.Workers)}
+}
+
+// Wrap returns handle unchanged if the cache is disabled, or a CachedHandle
+// layering write-back caching on top of it.
+func (c *Cache) Wrap(handle filesystem.FileHandle) filesystem.FileHandle {
+	if !c.cfg.Enabled {
+		return handle
+	}
+	return &CachedHandle{
+		cache:    c,
+		handle:   handle,
+		chunks:   make(map[int64]PageChunk),
+		reserved: make(map[int64]int64),
+		pipeline: NewUploadPipeline(handle, c.cfg.ChunkSize, c.pool),
+	}
+}
+
+func (c *Cache) reserve(n int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dirtyBytes+n > c.cfg.MemBudget && c.cfg.MemBudget > 0 {
+		return false
+	}
+	c.dirtyBytes += n
+	return true
+}
+
+func (c *Cache) release(n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirtyBytes -= n
+}
+
+// CachedHandle is a filesystem.FileHandle that stages writes in chunk-sized
+// windows and flushes them asynchronously via an UploadPipeline, merging
+// dirty chunks over the wrapped handle's data on read.
+type CachedHandle struct {
+	cache    *Cache
+	handle   filesystem.FileHandle
+	pipeline *UploadPipeline
+
+	mu       sync.Mutex
+	chunks   map[int64]PageChunk
+	reserved map[int64]int64 // chunk index -> bytes actually reserved against cache.dirtyBytes
+	pos      int64
+}
+
+func (h *CachedHandle) chunkIndex(offset int64) int64 { return offset / h.cache.cfg.ChunkSize }
+func (h *CachedHandle) chunkOffset(offset int64) int64 { return offset % h.cache.cfg.ChunkSize }
+
+// chunkFor returns the active chunk for index, creating a MemChunk (or a
+// SwapFileChunk if the memory budget is exhausted) on first touch.
+func (h *CachedHandle) chunkFor(index int64) (PageChunk, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if c, ok := h.chunks[index]; ok {
+		return c, nil
+	}
+
+	size := h.cache.cfg.ChunkSize
+	reserved := h.cache.reserve(size)
+	if !reserved {
+		// Over budget: spill an already-resident chunk (see
+		// spillOneLocked) to disk to make room before falling back to
+		// putting the new one straight on disk too, so memory pressure
+		// can move chunks touched before the budget filled up out of
+		// memory, not just whichever chunk happens to be touched next.
+		h.spillOneLocked()
+		reserved = h.cache.reserve(size)
+	}
+
+	var chunk PageChunk
+	if reserved {
+		chunk = NewMemChunk(size)
+		h.reserved[index] = size
+	} else {
+		sc, err := NewSwapFileChunk(h.cache.cfg.SwapDir, size)
+		if err != nil {
+			return nil, fmt.Errorf("pagecache: spill chunk %d to disk: %w", index, err)
+		}
+		chunk = sc
+	}
+	h.chunks[index] = chunk
+	return chunk, nil
+}
+
+// spillOneLocked converts one resident MemChunk (chosen arbitrarily — Go's
+// map iteration order) into a SwapFileChunk and releases the memory budget
+// it had reserved, making room for a new chunk under memory pressure. h.mu
+// must already be held by the caller. It is a no-op if every chunk is
+// already on disk.
+func (h *CachedHandle) spillOneLocked() {
+	for idx, c := range h.chunks {
+		mc, ok := c.(*MemChunk)
+		if !ok {
+			continue
+		}
+		sc, err := mc.Spill(h.cache.cfg.SwapDir)
+		if err != nil {
+			continue
+		}
+		h.chunks[idx] = sc
+		if n, ok := h.reserved[idx]; ok {
+			h.cache.release(n)
+			delete(h.reserved, idx)
+		}
+		return
+	}
+}
+
+// WriteAt stages p into the active chunk(s) covering [offset, offset+len(p))
+// and marks them dirty; nothing reaches the wrapped handle until Sync or
+// Close seals the chunks and hands them to the pipeline.
+func (h *CachedHandle) WriteAt(p []byte, offset int64) (int, error) {
+	written := 0
+	for written < len(p) {
+		idx := h.chunkIndex(offset)
+		coff := h.chunkOffset(offset)
+		chunk, err := h.chunkFor(idx)
+		if err != nil {
+			return written, err
+		}
+
+		n := int(h.cache.cfg.ChunkSize - coff)
+		if n > len(p)-written {
+			n = len(p) - written
+		}
+
+		if _, err := chunk.WriteAt(p[written:written+n], coff); err != nil {
+			return written, err
+		}
+
+		written += n
+		offset += int64(n)
+	}
+	return written, nil
+}
+
+func (h *CachedHandle) Write(p []byte) (int, error) {
+	n, err := h.WriteAt(p, h.pos)
+	h.pos += int64(n)
+	return n, err
+}
+
+// ReadAt reads from the wrapped handle and then overlays any dirty bytes
+// still held in the active chunks, so a read always observes the caller's
+// own unflushed writes. Dirty bytes past whatever the wrapped handle
+// returned (e.g. a write past the not-yet-flushed end of file) still count
+// towards the bytes returned, but the result otherwise follows the
+// io.ReaderAt contract: n < len(p) only comes with a non-nil error.
+func (h *CachedHandle) ReadAt(p []byte, offset int64) (int, error) {
+	n, err := h.handle.ReadAt(p, offset)
+	if err != nil && n == 0 && err != io.EOF {
+		return 0, err
+	}
+
+	end := offset + int64(len(p))
+	covered := int64(n)
+	for idx := h.chunkIndex(offset); idx*h.cache.cfg.ChunkSize < end; idx++ {
+		h.mu.Lock()
+		chunk, ok := h.chunks[idx]
+		h.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		chunkBase := idx * h.cache.cfg.ChunkSize
+		chunk.Dirty().Iterate(func(iv Interval) bool {
+			absStart := chunkBase + iv.Offset
+			absEnd := absStart + iv.Size
+			if absEnd <= offset || absStart >= end {
+				return true
+			}
+			lo := absStart
+			if lo < offset {
+				lo = offset
+			}
+			hi := absEnd
+			if hi > end {
+				hi = end
+			}
+			buf := make([]byte, hi-lo)
+			if _, rerr := chunk.ReadAt(buf, lo-chunkBase); rerr == nil {
+				copy(p[lo-offset:], buf)
+				if rel := hi - offset; rel > covered {
+					covered = rel
+				}
+			}
+			return true
+		})
+	}
+
+	if covered > int64(len(p)) {
+		covered = int64(len(p))
+	}
+	if covered == int64(len(p)) {
+		return int(covered), nil
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	return int(covered), err
+}
+
+func (h *CachedHandle) Read(p []byte) (int, error) {
+	n, err := h.ReadAt(p, h.pos)
+	h.pos += int64(n)
+	return n, err
+}
+
+func (h *CachedHandle) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 0:
+		h.pos = offset
+	case 1:
+		h.pos += offset
+	case 2:
+		info, err := h.handle.Stat()
+		if err != nil {
+			return 0, err
+		}
+		h.pos = info.Size + offset
+	default:
+		return 0, fmt.Errorf("pagecache: invalid whence %d", whence)
+	}
+	return h.pos, nil
+}
+
+func (h *CachedHandle) Stat() (filesystem.FileInfo, error) { return h.handle.Stat() }
+func (h *CachedHandle) Path() string                       { return h.handle.Path() }
+func (h *CachedHandle) Flags() filesystem.OpenFlag          { return h.handle.Flags() }
+
+// Sync seals every active chunk, releasing the memory budget each one had
+// reserved (a chunk reserves its full size up front in chunkFor, regardless
+// of how many bytes end up dirty, so that's what must be given back here),
+// and blocks until the pipeline has flushed all of them through the wrapped
+// handle, per AGFS_HandleSync's contract.
+func (h *CachedHandle) Sync() error {
+	h.mu.Lock()
+	sealed := make([]PageChunk, 0, len(h.chunks))
+	for idx, chunk := range h.chunks {
+		h.pipeline.Seal(idx, chunk)
+		sealed = append(sealed, chunk)
+		if n, ok := h.reserved[idx]; ok {
+			h.cache.release(n)
+			delete(h.reserved, idx)
+		}
+		delete(h.chunks, idx)
+	}
+	h.mu.Unlock()
+
+	if err := h.pipeline.Drain(); err != nil {
+		return fmt.Errorf("pagecache: flush dirty chunks: %w", err)
+	}
+
+	// Safe to close now: Drain only returns once every chunk sealed above
+	// has been flushed, so nothing will touch them again.
+	for _, chunk := range sealed {
+		chunk.Close()
+	}
+	return h.handle.Sync()
+}
+
+// Close flushes any remaining dirty chunks (which also releases their
+// reserved memory budget, see Sync) before closing the wrapped handle.
+func (h *CachedHandle) Close() error {
+	if err := h.Sync(); err != nil {
+		return err
+	}
+	h.pipeline.Close()
+	return h.handle.Close()
+}
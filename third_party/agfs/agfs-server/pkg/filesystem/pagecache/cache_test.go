@@ -0,0 +1,135 @@
+package pagecache
+
+import (
+	"testing"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+)
+
+// fakeHandle is a minimal in-memory filesystem.FileHandle for exercising
+// Cache/CachedHandle without a real mounted plugin.
+type fakeHandle struct {
+	data []byte
+	pos  int64
+}
+
+func (h *fakeHandle) ReadAt(p []byte, offset int64) (int, error) {
+	if offset >= int64(len(h.data)) {
+		return 0, nil
+	}
+	return copy(p, h.data[offset:]), nil
+}
+
+func (h *fakeHandle) Read(p []byte) (int, error) {
+	n, err := h.ReadAt(p, h.pos)
+	h.pos += int64(n)
+	return n, err
+}
+
+func (h *fakeHandle) WriteAt(p []byte, offset int64) (int, error) {
+	end := offset + int64(len(p))
+	if end > int64(len(h.data)) {
+		grown := make([]byte, end)
+		copy(grown, h.data)
+		h.data = grown
+	}
+	return copy(h.data[offset:], p), nil
+}
+
+func (h *fakeHandle) Write(p []byte) (int, error) {
+	n, err := h.WriteAt(p, h.pos)
+	h.pos += int64(n)
+	return n, err
+}
+
+func (h *fakeHandle) Seek(offset int64, whence int) (int64, error) {
+	h.pos = offset
+	return h.pos, nil
+}
+
+func (h *fakeHandle) Sync() error                             { return nil }
+func (h *fakeHandle) Stat() (filesystem.FileInfo, error)      { return filesystem.FileInfo{Size: int64(len(h.data))}, nil }
+func (h *fakeHandle) Close() error                            { return nil }
+func (h *fakeHandle) Path() string                            { return "/fake" }
+func (h *fakeHandle) Flags() filesystem.OpenFlag              { return 0 }
+
+func newTestCache(t *testing.T, cfg Config) (*Cache, *fakeHandle, *CachedHandle) {
+	t.Helper()
+	cfg.Enabled = true
+	cfg.ChunkSize = 16
+	cfg.SwapDir = t.TempDir()
+	c := New(cfg)
+	backing := &fakeHandle{}
+	wrapped := c.Wrap(backing)
+	ch, ok := wrapped.(*CachedHandle)
+	if !ok {
+		t.Fatal("Wrap: want *CachedHandle for an enabled cache")
+	}
+	return c, backing, ch
+}
+
+func TestCachedHandleWriteReadRoundTrip(t *testing.T) {
+	_, _, ch := newTestCache(t, Config{})
+
+	if _, err := ch.WriteAt([]byte("hello world"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+
+	buf := make([]byte, 11)
+	n, err := ch.ReadAt(buf, 0)
+	if err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	if n != 11 || string(buf) != "hello world" {
+		t.Fatalf("ReadAt = %q (n=%d), want %q", buf[:n], n, "hello world")
+	}
+}
+
+func TestCachedHandleSyncFlushesAndReleasesBudget(t *testing.T) {
+	c, backing, ch := newTestCache(t, Config{MemBudget: 1 << 20})
+
+	if _, err := ch.WriteAt([]byte("dirty"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	if err := ch.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if got := string(backing.data[:5]); got != "dirty" {
+		t.Fatalf("backing handle after Sync = %q, want %q", got, "dirty")
+	}
+
+	c.mu.Lock()
+	dirty := c.dirtyBytes
+	c.mu.Unlock()
+	if dirty != 0 {
+		t.Fatalf("dirtyBytes after Sync = %d, want 0 (budget not released)", dirty)
+	}
+}
+
+func TestChunkForSpillsResidentChunkUnderMemoryPressure(t *testing.T) {
+	// A budget of exactly one chunk's worth: touching a second chunk index
+	// must spill the first (already-resident) MemChunk to disk rather than
+	// only ever swapping newly-touched chunks.
+	_, _, ch := newTestCache(t, Config{MemBudget: 16})
+
+	first, err := ch.chunkFor(0)
+	if err != nil {
+		t.Fatalf("chunkFor(0): %v", err)
+	}
+	if _, ok := first.(*MemChunk); !ok {
+		t.Fatalf("chunkFor(0) = %T, want *MemChunk", first)
+	}
+
+	if _, err := ch.chunkFor(1); err != nil {
+		t.Fatalf("chunkFor(1): %v", err)
+	}
+
+	ch.mu.Lock()
+	spilled, ok := ch.chunks[0].(*SwapFileChunk)
+	ch.mu.Unlock()
+	if !ok {
+		t.Fatalf("chunk 0 = %T after touching chunk 1, want it spilled to *SwapFileChunk", ch.chunks[0])
+	}
+	spilled.Close()
+}
@@ -0,0 +1,163 @@
+package pagecache
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// PageChunk is one fixed-size, chunk-aligned window of a file's write-back
+// cache. Writes land in a PageChunk's backing storage and its dirty byte
+// ranges are tracked so a partially-written chunk can still be read back
+// correctly and flushed precisely.
+type PageChunk interface {
+	// ReadAt copies the chunk's stored bytes starting at the chunk-relative
+	// offset into p, dirty or not; bytes never written read as zero. Use
+	// Dirty to tell which of them hold written data.
+	ReadAt(p []byte, offset int64) (int, error)
+	// WriteAt writes p at the chunk-relative offset and marks that range
+	// dirty.
+	WriteAt(p []byte, offset int64) (int, error)
+	// Dirty returns the chunk's dirty interval list.
+	Dirty() *ChunkIntervalList
+	// Close releases any resources (e.g. the swap file) held by the chunk.
+	Close() error
+}
+
+// MemChunk is a PageChunk backed by a plain in-memory byte slice. It is
+// used while the Cache's MemChunk bytes stay within the configured memory
+// budget.
+type MemChunk struct {
+	mu    sync.Mutex
+	buf   []byte
+	dirty *ChunkIntervalList
+}
+
+// NewMemChunk allocates a MemChunk of exactly size bytes.
+func NewMemChunk(size int64) *MemChunk {
+	return &MemChunk{
+		buf:   make([]byte, size),
+		dirty: NewChunkIntervalList(),
+	}
+}
+
+func (c *MemChunk) ReadAt(p []byte, offset int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset >= int64(len(c.buf)) {
+		return 0, nil
+	}
+	n := copy(p, c.buf[offset:])
+	return n, nil
+}
+
+func (c *MemChunk) WriteAt(p []byte, offset int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := offset + int64(len(p))
+	if end > int64(len(c.buf)) {
+		grown := make([]byte, end)
+		copy(grown, c.buf)
+		c.buf = grown
+	}
+	n := copy(c.buf[offset:], p)
+	c.dirty.Insert(offset, int64(n))
+	return n, nil
+}
+
+func (c *MemChunk) Dirty() *ChunkIntervalList { return c.dirty }
+
+func (c *MemChunk) Close() error { return nil }
+
+// Spill copies this chunk's contents into a new SwapFileChunk backed by
+// dir, preserving the dirty-interval list, and returns it. The caller is
+// responsible for swapping the returned chunk in and discarding c.
+func (c *MemChunk) Spill(dir string) (*SwapFileChunk, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sc, err := NewSwapFileChunk(dir, int64(len(c.buf)))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sc.WriteAt(c.buf, 0); err != nil {
+		sc.Close()
+		return nil, err
+	}
+	sc.dirty = c.dirty
+	return sc, nil
+}
+
+// SwapFileChunk is a PageChunk backed by an mmapped temporary file, used
+// once the Cache's MemChunk bytes would exceed the configured memory budget
+// so chunks can be spilled out of the process's resident memory.
+type SwapFileChunk struct {
+	mu    sync.Mutex
+	file  *os.File
+	data  []byte
+	dirty *ChunkIntervalList
+}
+
+// NewSwapFileChunk creates a size-byte temp file under dir and mmaps it.
+func NewSwapFileChunk(dir string, size int64) (*SwapFileChunk, error) {
+	f, err := os.CreateTemp(dir, "openviking-pagecache-*.swap")
+	if err != nil {
+		return nil, fmt.Errorf("pagecache: create swap file: %w", err)
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("pagecache: truncate swap file: %w", err)
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("pagecache: mmap swap file: %w", err)
+	}
+
+	return &SwapFileChunk{
+		file:  f,
+		data:  data,
+		dirty: NewChunkIntervalList(),
+	}, nil
+}
+
+func (c *SwapFileChunk) ReadAt(p []byte, offset int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset >= int64(len(c.data)) {
+		return 0, nil
+	}
+	n := copy(p, c.data[offset:])
+	return n, nil
+}
+
+func (c *SwapFileChunk) WriteAt(p []byte, offset int64) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if offset+int64(len(p)) > int64(len(c.data)) {
+		return 0, fmt.Errorf("pagecache: write at %d+%d exceeds swap chunk size %d", offset, len(p), len(c.data))
+	}
+	n := copy(c.data[offset:], p)
+	c.dirty.Insert(offset, int64(n))
+	return n, nil
+}
+
+func (c *SwapFileChunk) Dirty() *ChunkIntervalList { return c.dirty }
+
+func (c *SwapFileChunk) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if c.data != nil {
+		err = syscall.Munmap(c.data)
+		c.data = nil
+	}
+	name := c.file.Name()
+	c.file.Close()
+	os.Remove(name)
+	return err
+}
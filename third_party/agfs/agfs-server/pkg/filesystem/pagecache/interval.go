@@ -0,0 +1,129 @@
+package pagecache
+
+// Interval is a half-open byte range [Offset, Offset+Size) within a single
+// chunk.
+type Interval struct {
+	Offset int64
+	Size   int64
+}
+
+func (iv Interval) end() int64 { return iv.Offset + iv.Size }
+
+// overlaps reports whether iv and other share at least one byte, or are
+// directly adjacent (so Insert can coalesce them into one run).
+func (iv Interval) overlaps(other Interval) bool {
+	return iv.Offset <= other.end() && other.Offset <= iv.end()
+}
+
+func (iv Interval) union(other Interval) Interval {
+	start := iv.Offset
+	if other.Offset < start {
+		start = other.Offset
+	}
+	end := iv.end()
+	if other.end() > end {
+		end = other.end()
+	}
+	return Interval{Offset: start, Size: end - start}
+}
+
+// intervalNode is one link in ChunkIntervalList's sorted singly-linked list.
+type intervalNode struct {
+	iv   Interval
+	next *intervalNode
+}
+
+// ChunkIntervalList tracks the dirty byte ranges within a single fixed-size
+// chunk as a sorted linked list of non-overlapping, non-adjacent intervals.
+// It is not safe for concurrent use; callers (PageChunk implementations)
+// serialize access with their own lock.
+type ChunkIntervalList struct {
+	head *intervalNode
+	n    int
+}
+
+// NewChunkIntervalList returns an empty interval list.
+func NewChunkIntervalList() *ChunkIntervalList {
+	return &ChunkIntervalList{}
+}
+
+// Insert marks [offset, offset+size) dirty, merging with any overlapping or
+// adjacent intervals already recorded.
+func (l *ChunkIntervalList) Insert(offset, size int64) {
+	if size <= 0 {
+		return
+	}
+	iv := Interval{Offset: offset, Size: size}
+
+	var prev *intervalNode
+	cur := l.head
+	for cur != nil && cur.iv.Offset <= iv.end() {
+		if cur.iv.overlaps(iv) {
+			iv = iv.union(cur.iv)
+			// Unlink cur; it has been absorbed into iv.
+			next := cur.next
+			if prev == nil {
+				l.head = next
+			} else {
+				prev.next = next
+			}
+			l.n--
+			cur = next
+			continue
+		}
+		prev = cur
+		cur = cur.next
+	}
+
+	node := &intervalNode{iv: iv}
+	if prev == nil {
+		node.next = l.head
+		l.head = node
+	} else {
+		node.next = prev.next
+		prev.next = node
+	}
+	l.n++
+}
+
+// Merge folds other's intervals into l.
+func (l *ChunkIntervalList) Merge(other *ChunkIntervalList) {
+	other.Iterate(func(iv Interval) bool {
+		l.Insert(iv.Offset, iv.Size)
+		return true
+	})
+}
+
+// Iterate walks the list in ascending offset order, stopping early if fn
+// returns false.
+func (l *ChunkIntervalList) Iterate(fn func(Interval) bool) {
+	for cur := l.head; cur != nil; cur = cur.next {
+		if !fn(cur.iv) {
+			return
+		}
+	}
+}
+
+// Len returns the number of disjoint dirty intervals currently tracked.
+func (l *ChunkIntervalList) Len() int { return l.n }
+
+// Reset clears every tracked interval, e.g. once a chunk has been flushed.
+func (l *ChunkIntervalList) Reset() {
+	l.head = nil
+	l.n = 0
+}
+
+// Covers reports whether [offset, offset+size) is fully contained in a
+// single tracked dirty interval.
+func (l *ChunkIntervalList) Covers(offset, size int64) bool {
+	want := Interval{Offset: offset, Size: size}
+	found := false
+	l.Iterate(func(iv Interval) bool {
+		if iv.Offset <= want.Offset && want.end() <= iv.end() {
+			found = true
+			return false
+		}
+		return true
+	})
+	return found
+}
@@ -0,0 +1,102 @@
+package pagecache
+
+import "testing"
+
+func collect(l *ChunkIntervalList) []Interval {
+	var out []Interval
+	l.Iterate(func(iv Interval) bool {
+		out = append(out, iv)
+		return true
+	})
+	return out
+}
+
+func TestChunkIntervalListInsertDisjoint(t *testing.T) {
+	l := NewChunkIntervalList()
+	l.Insert(100, 10)
+	l.Insert(0, 10)
+
+	got := collect(l)
+	want := []Interval{{Offset: 0, Size: 10}, {Offset: 100, Size: 10}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Insert disjoint = %v, want %v", got, want)
+	}
+	if l.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", l.Len())
+	}
+}
+
+func TestChunkIntervalListInsertOverlapping(t *testing.T) {
+	l := NewChunkIntervalList()
+	l.Insert(0, 10)
+	l.Insert(5, 10)
+
+	got := collect(l)
+	want := Interval{Offset: 0, Size: 15}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("Insert overlapping = %v, want [%v]", got, want)
+	}
+}
+
+func TestChunkIntervalListInsertAdjacentCoalesces(t *testing.T) {
+	l := NewChunkIntervalList()
+	l.Insert(0, 10)
+	l.Insert(10, 10)
+
+	got := collect(l)
+	want := Interval{Offset: 0, Size: 20}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("Insert adjacent = %v, want [%v]", got, want)
+	}
+}
+
+func TestChunkIntervalListInsertBridgesGap(t *testing.T) {
+	l := NewChunkIntervalList()
+	l.Insert(0, 10)
+	l.Insert(20, 10)
+	l.Insert(5, 20) // overlaps both existing intervals, should merge all three
+
+	got := collect(l)
+	want := Interval{Offset: 0, Size: 30}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("Insert bridging gap = %v, want [%v]", got, want)
+	}
+}
+
+func TestChunkIntervalListCovers(t *testing.T) {
+	l := NewChunkIntervalList()
+	l.Insert(10, 10) // [10, 20)
+
+	if !l.Covers(12, 5) {
+		t.Error("Covers(12, 5) = false, want true (fully inside [10,20))")
+	}
+	if l.Covers(5, 10) {
+		t.Error("Covers(5, 10) = true, want false (starts before the tracked interval)")
+	}
+	if l.Covers(15, 10) {
+		t.Error("Covers(15, 10) = true, want false (extends past the tracked interval)")
+	}
+}
+
+func TestChunkIntervalListReset(t *testing.T) {
+	l := NewChunkIntervalList()
+	l.Insert(0, 10)
+	l.Reset()
+
+	if l.Len() != 0 {
+		t.Fatalf("Len() after Reset = %d, want 0", l.Len())
+	}
+	if got := collect(l); len(got) != 0 {
+		t.Fatalf("Iterate after Reset = %v, want empty", got)
+	}
+}
+
+func TestChunkIntervalListInsertIgnoresNonPositiveSize(t *testing.T) {
+	l := NewChunkIntervalList()
+	l.Insert(0, 0)
+	l.Insert(0, -5)
+
+	if l.Len() != 0 {
+		t.Fatalf("Len() after no-op inserts = %d, want 0", l.Len())
+	}
+}
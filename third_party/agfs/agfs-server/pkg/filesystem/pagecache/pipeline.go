@@ -0,0 +1,187 @@
+package pagecache
+
+import (
+	"sync"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+)
+
+// sealedChunk is a chunk handed over by CachedHandle.Sync (or Close) and
+// ready to be written back through the underlying handle.
+type sealedChunk struct {
+	index int64
+	chunk PageChunk
+}
+
+// workerPool bounds how many flushes may be in flight at once across every
+// UploadPipeline sharing it, so a burst of writes to many files can't spawn
+// unbounded goroutines.
+type workerPool struct {
+	sem chan struct{}
+}
+
+func newWorkerPool(workers int) *workerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &workerPool{sem: make(chan struct{}, workers)}
+}
+
+// run executes fn on a pool goroutine and blocks the caller until fn
+// returns. Pipelines call this from their single dispatcher loop, so
+// blocking here is what keeps flushes for one file strictly ordered while
+// still letting unrelated files flush concurrently.
+func (p *workerPool) run(fn func()) {
+	p.sem <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() { <-p.sem }()
+		fn()
+	}()
+	<-done
+}
+
+// UploadPipeline hands a single file handle's sealed chunks off to a shared
+// worker pool that flushes them via the wrapped handle's WriteAt, one at a
+// time and strictly in the order they were sealed. It does not limit how
+// many chunks a CachedHandle keeps active: chunks are sealed only when the
+// handle is synced or closed, not as writes move on.
+type UploadPipeline struct {
+	handle    filesystem.FileHandle
+	chunkSize int64
+	pool      *workerPool
+
+	mu       sync.Mutex
+	queue    []sealedChunk
+	inFlight int
+	notify   chan struct{}
+	closed   bool
+	drained  chan struct{}
+
+	flushErrMu sync.Mutex
+	flushErr   error
+}
+
+// NewUploadPipeline starts a dispatcher goroutine that drains sealed chunks
+// for handle in FIFO order, using pool to bound overall flush concurrency.
+func NewUploadPipeline(handle filesystem.FileHandle, chunkSize int64, pool *workerPool) *UploadPipeline {
+	p := &UploadPipeline{
+		handle:    handle,
+		chunkSize: chunkSize,
+		pool:      pool,
+		notify:    make(chan struct{}, 1),
+		drained:   make(chan struct{}, 1),
+	}
+	go p.dispatch()
+	return p
+}
+
+// Seal hands a cold chunk to the pipeline for flushing. It never blocks the
+// caller on I/O; the actual WriteAt happens on the dispatcher goroutine.
+func (p *UploadPipeline) Seal(index int64, chunk PageChunk) {
+	p.mu.Lock()
+	p.queue = append(p.queue, sealedChunk{index: index, chunk: chunk})
+	p.mu.Unlock()
+
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (p *UploadPipeline) dispatch() {
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			if p.closed {
+				p.mu.Unlock()
+				select {
+				case p.drained <- struct{}{}:
+				default:
+				}
+				return
+			}
+			p.mu.Unlock()
+			<-p.notify
+			continue
+		}
+		job := p.queue[0]
+		p.queue = p.queue[1:]
+		p.inFlight++
+		p.mu.Unlock()
+
+		p.flush(job)
+
+		p.mu.Lock()
+		p.inFlight--
+		p.mu.Unlock()
+
+		select {
+		case p.drained <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (p *UploadPipeline) flush(job sealedChunk) {
+	var writeErr error
+	job.chunk.Dirty().Iterate(func(iv Interval) bool {
+		buf := make([]byte, iv.Size)
+		if _, err := job.chunk.ReadAt(buf, iv.Offset); err != nil {
+			writeErr = err
+			return false
+		}
+
+		absOffset := job.index*p.chunkSize + iv.Offset
+		p.pool.run(func() {
+			if _, err := p.handle.WriteAt(buf, absOffset); err != nil {
+				writeErr = err
+			}
+		})
+		return writeErr == nil
+	})
+
+	if writeErr != nil {
+		p.flushErrMu.Lock()
+		if p.flushErr == nil {
+			p.flushErr = writeErr
+		}
+		p.flushErrMu.Unlock()
+	}
+}
+
+// Drain blocks until every chunk sealed so far (and any sealed while
+// waiting) has been flushed, then returns the first flush error observed,
+// if any. AGFS_HandleSync calls this so sync is durable. A chunk removed
+// from the queue but still mid-flush (dispatch tracks this via inFlight)
+// counts as not-yet-drained, so callers like CachedHandle.Sync that close
+// sealed chunks right after Drain returns can't race flush's ReadAt against
+// the chunk going away.
+func (p *UploadPipeline) Drain() error {
+	for {
+		p.mu.Lock()
+		idle := len(p.queue) == 0 && p.inFlight == 0
+		p.mu.Unlock()
+		if idle {
+			break
+		}
+		<-p.drained
+	}
+
+	p.flushErrMu.Lock()
+	defer p.flushErrMu.Unlock()
+	return p.flushErr
+}
+
+// Close stops the dispatcher once its queue is empty. Callers must Drain
+// before Close if they need to observe a final flush error.
+func (p *UploadPipeline) Close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
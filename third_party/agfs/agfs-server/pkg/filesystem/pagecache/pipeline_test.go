@@ -0,0 +1,113 @@
+package pagecache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingHandle lets a test hold WriteAt open until released, so Drain can
+// be exercised against a flush that's still in flight.
+type blockingHandle struct {
+	fakeHandle
+	release chan struct{}
+	writes  int32
+}
+
+func (h *blockingHandle) WriteAt(p []byte, offset int64) (int, error) {
+	<-h.release
+	atomic.AddInt32(&h.writes, 1)
+	return h.fakeHandle.WriteAt(p, offset)
+}
+
+func TestUploadPipelineDrainWaitsForInFlightFlush(t *testing.T) {
+	backing := &blockingHandle{release: make(chan struct{})}
+	pool := newWorkerPool(1)
+	p := NewUploadPipeline(backing, 16, pool)
+	defer p.Close()
+
+	chunk := NewMemChunk(16)
+	if _, err := chunk.WriteAt([]byte("hello"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	p.Seal(0, chunk)
+
+	// Give dispatch a chance to dequeue the job and block inside flush's
+	// WriteAt before we ask Drain to observe it.
+	time.Sleep(10 * time.Millisecond)
+
+	drained := make(chan error, 1)
+	go func() { drained <- p.Drain() }()
+
+	select {
+	case <-drained:
+		t.Fatal("Drain returned before the in-flight flush finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(backing.release)
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if atomic.LoadInt32(&backing.writes) != 1 {
+		t.Fatalf("writes = %d, want 1", backing.writes)
+	}
+}
+
+func TestUploadPipelineDrainReportsFlushError(t *testing.T) {
+	backing := &fakeHandle{}
+	pool := newWorkerPool(1)
+	p := NewUploadPipeline(&erroringHandle{fakeHandle: backing}, 16, pool)
+	defer p.Close()
+
+	chunk := NewMemChunk(16)
+	if _, err := chunk.WriteAt([]byte("x"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	p.Seal(0, chunk)
+
+	if err := p.Drain(); err == nil {
+		t.Fatal("Drain: want non-nil error from a failing WriteAt")
+	}
+}
+
+type erroringHandle struct {
+	*fakeHandle
+}
+
+var errWriteFailed = errors.New("pagecache: simulated write failure")
+
+func (h *erroringHandle) WriteAt(p []byte, offset int64) (int, error) {
+	return 0, errWriteFailed
+}
+
+func TestWorkerPoolBoundsConcurrency(t *testing.T) {
+	pool := newWorkerPool(2)
+	var inFlight, maxInFlight int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.run(func() {
+				n := atomic.AddInt32(&inFlight, 1)
+				for {
+					max := atomic.LoadInt32(&maxInFlight)
+					if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&inFlight, -1)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Fatalf("max concurrent pool.run = %d, want <= 2", maxInFlight)
+	}
+}
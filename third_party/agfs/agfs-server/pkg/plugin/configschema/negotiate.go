@@ -0,0 +1,129 @@
+// Package configschema versions a plugin's mount config and negotiates the
+// capabilities a mount attempt requires against what the plugin actually
+// supports, the same way CNI versions its network config and negotiates
+// supported features per plugin. It lets plugin binaries be upgraded in
+// place: an older stored config is converted forward before the mount
+// proceeds, and a mount requesting a capability the loaded plugin binary
+// doesn't have fails loudly instead of silently degrading.
+package configschema
+
+import "fmt"
+
+// VersionedPlugin is the part of plugin.ServicePlugin this package needs.
+// Every ServicePlugin is expected to implement it: ConfigVersions lists the
+// config schema versions the plugin understands (newest last),
+// SupportedCapabilities lists the optional features it implements (e.g.
+// "stream", "handlefs", "digest"), and ConvertConfig upgrades a raw config
+// map from an older version to a newer one.
+type VersionedPlugin interface {
+	Name() string
+	ConfigVersions() []string
+	SupportedCapabilities() []string
+	ConvertConfig(fromVersion, toVersion string, raw map[string]interface{}) (map[string]interface{}, error)
+}
+
+// configVersionKey is the well-known key a stored mount config uses to
+// record which schema version it was written against. Configs without it
+// are assumed to be the plugin's oldest declared version.
+const configVersionKey = "config_version"
+
+// requiredCapabilitiesKey lists the capabilities (e.g. "stream") the mount
+// attempt itself requires, independent of what the plugin happens to
+// support.
+const requiredCapabilitiesKey = "required_capabilities"
+
+// Error is a structured negotiation failure. ID is stable across releases
+// so callers (including the AGFS_Mount C ABI) can branch on it instead of
+// matching error strings.
+type Error struct {
+	ID      string
+	Message string
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s: %s", e.ID, e.Message) }
+
+const (
+	// ErrUnsupportedConfigVersion means the stored config's version is not
+	// one the loaded plugin binary declares (e.g. a newer version it can't
+	// downgrade from).
+	ErrUnsupportedConfigVersion = "unsupported_config_version"
+	// ErrUnsupportedCapability means the mount attempt required a
+	// capability the loaded plugin binary doesn't support.
+	ErrUnsupportedCapability = "unsupported_capability"
+)
+
+// Negotiate upgrades rawConfig to the plugin's newest declared config
+// version (via one ConvertConfig(from, newest) call) and verifies every capability
+// listed under "required_capabilities" in rawConfig is present in the
+// plugin's SupportedCapabilities. It returns the upgraded config ready to
+// pass to the plugin's Mount/instance-construction call.
+func Negotiate(p VersionedPlugin, rawConfig map[string]interface{}) (map[string]interface{}, error) {
+	versions := p.ConfigVersions()
+	if len(versions) == 0 {
+		if err := checkCapabilities(p, rawConfig); err != nil {
+			return nil, err
+		}
+		return rawConfig, nil
+	}
+	newest := versions[len(versions)-1]
+
+	from, _ := rawConfig[configVersionKey].(string)
+	if from == "" {
+		from = versions[0]
+	}
+
+	config := rawConfig
+	if from != newest {
+		if !contains(versions, from) {
+			return nil, &Error{
+				ID:      ErrUnsupportedConfigVersion,
+				Message: fmt.Sprintf("plugin %s does not recognize config version %q", p.Name(), from),
+			}
+		}
+
+		upgraded, err := p.ConvertConfig(from, newest, rawConfig)
+		if err != nil {
+			return nil, fmt.Errorf("configschema: convert %s config from %s to %s: %w", p.Name(), from, newest, err)
+		}
+		config = upgraded
+	}
+
+	if err := checkCapabilities(p, config); err != nil {
+		return nil, err
+	}
+
+	config[configVersionKey] = newest
+	return config, nil
+}
+
+func checkCapabilities(p VersionedPlugin, config map[string]interface{}) error {
+	required, _ := config[requiredCapabilitiesKey].([]interface{})
+	if len(required) == 0 {
+		return nil
+	}
+
+	supported := make(map[string]bool, len(p.SupportedCapabilities()))
+	for _, c := range p.SupportedCapabilities() {
+		supported[c] = true
+	}
+
+	for _, rc := range required {
+		name, _ := rc.(string)
+		if !supported[name] {
+			return &Error{
+				ID:      ErrUnsupportedCapability,
+				Message: fmt.Sprintf("plugin %s does not support capability %q", p.Name(), name),
+			}
+		}
+	}
+	return nil
+}
+
+func contains(versions []string, v string) bool {
+	for _, candidate := range versions {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package configschema
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakePlugin is a minimal VersionedPlugin for exercising Negotiate.
+type fakePlugin struct {
+	name         string
+	versions     []string
+	capabilities []string
+	convertErr   error
+	convertedFrom string
+}
+
+func (p *fakePlugin) Name() string              { return p.name }
+func (p *fakePlugin) ConfigVersions() []string   { return p.versions }
+func (p *fakePlugin) SupportedCapabilities() []string { return p.capabilities }
+
+func (p *fakePlugin) ConvertConfig(from, to string, raw map[string]interface{}) (map[string]interface{}, error) {
+	if p.convertErr != nil {
+		return nil, p.convertErr
+	}
+	p.convertedFrom = from
+	out := make(map[string]interface{}, len(raw)+1)
+	for k, v := range raw {
+		out[k] = v
+	}
+	out[configVersionKey] = to
+	return out, nil
+}
+
+func TestNegotiateNoDeclaredVersionsPassesThrough(t *testing.T) {
+	p := &fakePlugin{name: "nofs"}
+	raw := map[string]interface{}{"foo": "bar"}
+
+	got, err := Negotiate(p, raw)
+	if err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+	if got["foo"] != "bar" {
+		t.Fatalf("Negotiate() = %v, want unchanged config", got)
+	}
+}
+
+func TestNegotiateUpgradesOldConfig(t *testing.T) {
+	p := &fakePlugin{name: "vfs", versions: []string{"v1", "v2"}}
+	raw := map[string]interface{}{configVersionKey: "v1"}
+
+	got, err := Negotiate(p, raw)
+	if err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+	if got[configVersionKey] != "v2" {
+		t.Fatalf("Negotiate()[%s] = %v, want v2", configVersionKey, got[configVersionKey])
+	}
+}
+
+func TestNegotiateDefaultsMissingVersionToOldest(t *testing.T) {
+	p := &fakePlugin{name: "vfs", versions: []string{"v1", "v2"}}
+	raw := map[string]interface{}{}
+
+	got, err := Negotiate(p, raw)
+	if err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+	if p.convertedFrom != "v1" {
+		t.Fatalf("ConvertConfig from = %q, want v1 (the oldest declared version)", p.convertedFrom)
+	}
+	if got[configVersionKey] != "v2" {
+		t.Fatalf("Negotiate()[%s] = %v, want v2", configVersionKey, got[configVersionKey])
+	}
+}
+
+func TestNegotiateUnsupportedConfigVersion(t *testing.T) {
+	p := &fakePlugin{name: "vfs", versions: []string{"v1", "v2"}}
+	raw := map[string]interface{}{configVersionKey: "v99"}
+
+	_, err := Negotiate(p, raw)
+	if err == nil {
+		t.Fatal("Negotiate: want error for unrecognized config version, got nil")
+	}
+	var negErr *Error
+	if !errors.As(err, &negErr) || negErr.ID != ErrUnsupportedConfigVersion {
+		t.Fatalf("Negotiate error = %v, want *Error with ID %s", err, ErrUnsupportedConfigVersion)
+	}
+}
+
+func TestNegotiateRequiredCapabilitySupported(t *testing.T) {
+	p := &fakePlugin{name: "vfs", versions: []string{"v1"}, capabilities: []string{"stream"}}
+	raw := map[string]interface{}{
+		configVersionKey:         "v1",
+		requiredCapabilitiesKey:  []interface{}{"stream"},
+	}
+
+	if _, err := Negotiate(p, raw); err != nil {
+		t.Fatalf("Negotiate: %v", err)
+	}
+}
+
+func TestNegotiateRequiredCapabilityMissing(t *testing.T) {
+	p := &fakePlugin{name: "vfs", versions: []string{"v1"}, capabilities: []string{"digest"}}
+	raw := map[string]interface{}{
+		configVersionKey:        "v1",
+		requiredCapabilitiesKey: []interface{}{"stream"},
+	}
+
+	_, err := Negotiate(p, raw)
+	if err == nil {
+		t.Fatal("Negotiate: want error for unsupported capability, got nil")
+	}
+	var negErr *Error
+	if !errors.As(err, &negErr) || negErr.ID != ErrUnsupportedCapability {
+		t.Fatalf("Negotiate error = %v, want *Error with ID %s", err, ErrUnsupportedCapability)
+	}
+}
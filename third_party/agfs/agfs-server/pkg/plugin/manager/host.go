@@ -0,0 +1,208 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hostBinary is the child process that actually dlopens the plugin .so and
+// speaks the framed RPC protocol over a Unix socket. It is expected to sit
+// next to the embedder binary or on $PATH.
+const hostBinary = "openviking-plugin-host"
+
+// host supervises a single child process hosting one shared-library plugin.
+// A host is considered crashed once its Unix-socket connection errors out;
+// the manager re-forks it lazily, on the next call that needs it.
+type host struct {
+	libraryPath string
+	socketPath  string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	conn   net.Conn
+	nextID uint64
+
+	// onCrash is invoked (by the owning Manager) after a dead connection is
+	// detected and the child has been re-forked, so the manager can replay
+	// whatever open handles it is tracking for this plugin.
+	onCrash func() error
+}
+
+func newHost(libraryPath, socketDir string) *host {
+	return &host{
+		libraryPath: libraryPath,
+		socketPath:  fmt.Sprintf("%s/%d.sock", socketDir, atomic.AddUint32(&globalHostSeq, 1)),
+	}
+}
+
+var globalHostSeq uint32
+
+// ensureRunning forks the host binary if it isn't already running and
+// dials its control socket. It is safe to call repeatedly; a live host is a
+// no-op.
+func (h *host) ensureRunning() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.conn != nil {
+		return nil
+	}
+
+	_ = os.Remove(h.socketPath)
+
+	h.cmd = exec.Command(hostBinary, "-plugin", h.libraryPath, "-socket", h.socketPath)
+	h.cmd.Stdout = os.Stderr
+	h.cmd.Stderr = os.Stderr
+	if err := h.cmd.Start(); err != nil {
+		return fmt.Errorf("manager: start plugin host for %s: %w", h.libraryPath, err)
+	}
+
+	conn, err := dialWithRetry(h.socketPath, 2*time.Second)
+	if err != nil {
+		_ = h.cmd.Process.Kill()
+		return fmt.Errorf("manager: connect to plugin host for %s: %w", h.libraryPath, err)
+	}
+
+	h.conn = conn
+	return nil
+}
+
+func dialWithRetry(socketPath string, timeout time.Duration) (net.Conn, error) {
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("unix", socketPath)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		time.Sleep(20 * time.Millisecond)
+	}
+	return nil, lastErr
+}
+
+// call sends method(args) to the host, transparently restarting the child
+// and retrying exactly once if the connection has died (the crash-recovery
+// path). Open file handles are replayed against the new process before the
+// retry so callers keep seeing the same handle IDs.
+func (h *host) call(method string, args interface{}, result interface{}) error {
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := h.ensureRunning(); err != nil {
+			return err
+		}
+
+		if err := h.doCall(method, args, result); err != nil {
+			if attempt == 0 && isConnError(err) {
+				h.markCrashed()
+				if err := h.ensureRunning(); err != nil {
+					return err
+				}
+				if h.onCrash != nil {
+					if rerr := h.onCrash(); rerr != nil {
+						return fmt.Errorf("manager: replay open handles after crash: %w", rerr)
+					}
+				}
+				continue
+			}
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("manager: host for %s unavailable after restart", h.libraryPath)
+}
+
+func (h *host) doCall(method string, args interface{}, result interface{}) error {
+	h.mu.Lock()
+	conn := h.conn
+	h.nextID++
+	id := h.nextID
+	h.mu.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("manager: host for %s not connected", h.libraryPath)
+	}
+
+	rawArgs, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("manager: encode args for %s: %w", method, err)
+	}
+
+	req := Request{ID: id, Method: method, Args: rawArgs}
+	reqData, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("manager: encode request for %s: %w", method, err)
+	}
+
+	if err := WriteFrame(conn, reqData); err != nil {
+		return err
+	}
+
+	respData, err := ReadFrame(conn)
+	if err != nil {
+		return err
+	}
+
+	var resp Response
+	if err := json.Unmarshal(respData, &resp); err != nil {
+		return fmt.Errorf("manager: decode response for %s: %w", method, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("manager: decode result for %s: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// markCrashed tears down the stale connection/process so the next
+// ensureRunning forks a fresh child.
+func (h *host) markCrashed() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn != nil {
+		_ = h.conn.Close()
+		h.conn = nil
+	}
+	if h.cmd != nil && h.cmd.Process != nil {
+		_ = h.cmd.Process.Kill()
+	}
+}
+
+// isConnError reports whether err indicates the child's socket died, the
+// crash signature call retries on. A crashed child most commonly shows up
+// as io.EOF/io.ErrUnexpectedEOF from ReadFrame (the socket closed mid-read)
+// or a *net.OpError from the transport; WriteFrame, and ReadFrame past the
+// length header, wrap the underlying error with fmt.Errorf("...: %w", err),
+// so this must unwrap with errors.As/errors.Is rather than a bare type
+// assertion.
+func isConnError(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+func (h *host) shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn != nil {
+		_ = h.conn.Close()
+		h.conn = nil
+	}
+	if h.cmd != nil && h.cmd.Process != nil {
+		_ = h.cmd.Process.Kill()
+	}
+	_ = os.Remove(h.socketPath)
+}
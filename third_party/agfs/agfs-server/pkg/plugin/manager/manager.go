@@ -0,0 +1,229 @@
+// Package manager runs external plugin shared libraries in supervised child
+// processes instead of loading them in-process via Go's plugin package, so
+// that a crash inside a plugin (e.g. a segfault in a .so) can't take down
+// the embedder. Each child is a small openviking-plugin-host binary talking
+// back to the Manager over a length-prefixed Unix-socket protocol.
+package manager
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin"
+)
+
+// handleRef records enough about a caller's open handle to replay it
+// against a freshly re-forked child after a crash.
+type handleRef struct {
+	libraryPath string
+	path        string
+	flags       filesystem.OpenFlag
+	mode        uint32
+	hostID      int64
+}
+
+// Manager owns the set of out-of-process plugins loaded via LoadExternalPlugin
+// and keeps them mounted transparently across host restarts.
+type Manager struct {
+	mu         sync.RWMutex
+	registry   *Registry
+	socketDir  string
+	hosts      map[string]*host // library path -> host
+	handles    map[int64]*handleRef
+	nextHandle int64
+}
+
+// New creates a Manager whose on-disk registry lives at registryPath and
+// whose Unix sockets are created under socketDir.
+func New(registryPath, socketDir string) (*Manager, error) {
+	reg, err := NewRegistry(registryPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(socketDir, 0o755); err != nil {
+		return nil, fmt.Errorf("manager: create socket dir %s: %w", socketDir, err)
+	}
+
+	m := &Manager{
+		registry:  reg,
+		socketDir: socketDir,
+		hosts:     make(map[string]*host),
+		handles:   make(map[int64]*handleRef),
+	}
+	return m, nil
+}
+
+// Recover re-forks a child for every plugin previously recorded in the
+// registry. Call this once at startup so a restarted manager transparently
+// resumes the mounts it was supervising.
+//
+// A single entry failing to recover (e.g. the openviking-plugin-host binary
+// isn't on $PATH, or the .so was removed) is logged and skipped rather than
+// treated as fatal: the registry persists across restarts, so a hard error
+// here would mean every future process start fails until the stale entry is
+// manually cleared — a reliability regression from the very feature meant
+// to improve reliability. Callers that need in-process fallback for a
+// specific plugin (as AGFS_LoadPlugin does) should retry via Load after
+// Recover returns.
+func (m *Manager) Recover(remount func(libraryPath string, mountPoints []string, config map[string]interface{}) error) error {
+	for _, entry := range m.registry.List() {
+		if _, err := m.Load(entry.LibraryPath); err != nil {
+			log.Printf("manager: recover %s: %v (skipping)", entry.LibraryPath, err)
+			continue
+		}
+		if remount != nil {
+			if err := remount(entry.LibraryPath, entry.MountPoints, entry.Config); err != nil {
+				log.Printf("manager: remount %s: %v (skipping)", entry.LibraryPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Load forks (or reuses) the child process hosting libraryPath and returns a
+// plugin.ServicePlugin whose filesystem operations are transparently
+// proxied to it. No client-visible state is kept in-process beyond this
+// thin remote.
+func (m *Manager) Load(libraryPath string) (plugin.ServicePlugin, error) {
+	m.mu.Lock()
+	h, ok := m.hosts[libraryPath]
+	if !ok {
+		h = newHost(libraryPath, m.socketDir)
+		h.onCrash = func() error { return m.replay(libraryPath) }
+		m.hosts[libraryPath] = h
+	}
+	m.mu.Unlock()
+
+	if err := h.ensureRunning(); err != nil {
+		return nil, err
+	}
+
+	var name string
+	if err := h.call("Plugin.Name", nil, &name); err != nil {
+		return nil, fmt.Errorf("manager: query plugin name for %s: %w", libraryPath, err)
+	}
+
+	// Preserve any mount points/config already recorded for libraryPath
+	// (set via SetMountPoints after a previous mount) instead of clobbering
+	// them with a zero-value entry — Recover depends on this surviving
+	// repeated Load calls across restarts.
+	entry, _ := m.registry.Get(libraryPath)
+	entry.LibraryPath = libraryPath
+	if err := m.registry.Put(entry); err != nil {
+		return nil, err
+	}
+
+	return &remotePlugin{manager: m, libraryPath: libraryPath, host: h, name: name}, nil
+}
+
+// Unload kills the child process for libraryPath and drops it from the
+// registry so a future Recover won't re-fork it.
+func (m *Manager) Unload(libraryPath string) error {
+	m.mu.Lock()
+	h, ok := m.hosts[libraryPath]
+	delete(m.hosts, libraryPath)
+	m.mu.Unlock()
+
+	if ok {
+		h.shutdown()
+	}
+	return m.registry.Delete(libraryPath)
+}
+
+// SetMountPoints records the mount points an already-loaded plugin serves,
+// so Recover can re-mount them after a parent restart. libraryPath must
+// already have an entry from a prior Load call; mount attempts for fstypes
+// this manager never loaded (e.g. a builtin plugin mounted by factory name)
+// are silently not persisted, since there is nothing for Recover to re-fork.
+func (m *Manager) SetMountPoints(libraryPath string, mountPoints []string, config map[string]interface{}) error {
+	entry, ok := m.registry.Get(libraryPath)
+	if !ok {
+		return nil
+	}
+	entry.MountPoints = mountPoints
+	entry.Config = config
+	return m.registry.Put(entry)
+}
+
+// MountPoints returns the mount points currently recorded for libraryPath,
+// or nil if it isn't a plugin this manager loaded.
+func (m *Manager) MountPoints(libraryPath string) []string {
+	entry, ok := m.registry.Get(libraryPath)
+	if !ok {
+		return nil
+	}
+	return entry.MountPoints
+}
+
+func (m *Manager) trackHandle(libraryPath, path string, flags filesystem.OpenFlag, mode uint32, hostID int64) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextHandle++
+	id := m.nextHandle
+	m.handles[id] = &handleRef{
+		libraryPath: libraryPath,
+		path:        path,
+		flags:       flags,
+		mode:        mode,
+		hostID:      hostID,
+	}
+	return id
+}
+
+func (m *Manager) untrackHandle(id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.handles, id)
+}
+
+// currentHostID returns the host-local handle ID currently backing the
+// manager-issued mgrID. It changes across a crash replay, so callers must
+// look it up per-call rather than caching the value from OpenHandle.
+func (m *Manager) currentHostID(mgrID int64) (int64, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ref, ok := m.handles[mgrID]
+	if !ok {
+		return 0, false
+	}
+	return ref.hostID, true
+}
+
+// replay re-opens every handle the manager still considers live for
+// libraryPath against the freshly re-forked host, updating the stored
+// host-local handle ID so the manager-issued ID callers hold keeps working.
+func (m *Manager) replay(libraryPath string) error {
+	m.mu.RLock()
+	h := m.hosts[libraryPath]
+	var refs []*handleRef
+	for _, ref := range m.handles {
+		if ref.libraryPath == libraryPath {
+			refs = append(refs, ref)
+		}
+	}
+	m.mu.RUnlock()
+
+	if h == nil {
+		return nil
+	}
+
+	for _, ref := range refs {
+		var hostID int64
+		args := struct {
+			Path  string `json:"path"`
+			Flags int    `json:"flags"`
+			Mode  uint32 `json:"mode"`
+		}{Path: ref.path, Flags: int(ref.flags), Mode: ref.mode}
+
+		if err := h.doCall("Handle.Open", args, &hostID); err != nil {
+			return fmt.Errorf("manager: replay handle for %s: %w", ref.path, err)
+		}
+		m.mu.Lock()
+		ref.hostID = hostID
+		m.mu.Unlock()
+	}
+	return nil
+}
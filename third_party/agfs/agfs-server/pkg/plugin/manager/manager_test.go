@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	m, err := New(filepath.Join(dir, "registry.json"), filepath.Join(dir, "sockets"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return m
+}
+
+func TestSetMountPointsNoOpForUnloadedLibrary(t *testing.T) {
+	m := newTestManager(t)
+
+	// Nothing ever Load()ed "/plugins/a.so"; SetMountPoints must not
+	// fabricate a registry entry for it (that would make Recover try to
+	// fork a plugin host for a builtin fstype that was never loaded).
+	if err := m.SetMountPoints("/plugins/a.so", []string{"/mnt/a"}, nil); err != nil {
+		t.Fatalf("SetMountPoints: %v", err)
+	}
+	if mps := m.MountPoints("/plugins/a.so"); mps != nil {
+		t.Fatalf("MountPoints = %v, want nil (never loaded)", mps)
+	}
+}
+
+func TestSetMountPointsPersistsForLoadedLibrary(t *testing.T) {
+	m := newTestManager(t)
+
+	// Simulate what Load does on success: Put an entry for the library
+	// path before any mount point is known.
+	if err := m.registry.Put(RegistryEntry{LibraryPath: "/plugins/a.so"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if err := m.SetMountPoints("/plugins/a.so", []string{"/mnt/a"}, map[string]interface{}{"k": "v"}); err != nil {
+		t.Fatalf("SetMountPoints: %v", err)
+	}
+
+	if got := m.MountPoints("/plugins/a.so"); !reflect.DeepEqual(got, []string{"/mnt/a"}) {
+		t.Fatalf("MountPoints = %v, want [/mnt/a]", got)
+	}
+
+	entry, ok := m.registry.Get("/plugins/a.so")
+	if !ok {
+		t.Fatal("registry.Get: entry missing")
+	}
+	if entry.Config["k"] != "v" {
+		t.Fatalf("Config = %v, want k=v", entry.Config)
+	}
+}
+
+func TestLoadPreservesExistingMountPoints(t *testing.T) {
+	m := newTestManager(t)
+
+	// Populate the registry the way a prior AGFS_Mount + SetMountPoints
+	// would have, then replicate Load's registry.Put call directly (rather
+	// than calling Load, which would try to fork a real child process) to
+	// verify it round-trips MountPoints/Config instead of clobbering them.
+	if err := m.registry.Put(RegistryEntry{
+		LibraryPath: "/plugins/a.so",
+		MountPoints: []string{"/mnt/a"},
+		Config:      map[string]interface{}{"k": "v"},
+	}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	entry, _ := m.registry.Get("/plugins/a.so")
+	entry.LibraryPath = "/plugins/a.so"
+	if err := m.registry.Put(entry); err != nil {
+		t.Fatalf("Put (read-modify-write like Load): %v", err)
+	}
+
+	got, _ := m.registry.Get("/plugins/a.so")
+	if !reflect.DeepEqual(got.MountPoints, []string{"/mnt/a"}) {
+		t.Fatalf("MountPoints after reload-style Put = %v, want [/mnt/a]", got.MountPoints)
+	}
+}
@@ -0,0 +1,111 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RegistryEntry describes a single out-of-process plugin that the manager
+// is responsible for keeping alive across host restarts.
+type RegistryEntry struct {
+	LibraryPath string                 `json:"library_path"`
+	MountPoints []string               `json:"mount_points"`
+	Config      map[string]interface{} `json:"config"`
+}
+
+// Registry persists RegistryEntry records to a single JSON file so that a
+// freshly started manager can re-fork and re-mount every child it was
+// supervising before the parent process went away.
+type Registry struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]RegistryEntry
+}
+
+// NewRegistry loads the registry at path if it exists, or starts empty.
+func NewRegistry(path string) (*Registry, error) {
+	r := &Registry{
+		path:    path,
+		entries: make(map[string]RegistryEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("manager: read registry %s: %w", path, err)
+	}
+
+	var entries []RegistryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("manager: decode registry %s: %w", path, err)
+	}
+	for _, e := range entries {
+		r.entries[e.LibraryPath] = e
+	}
+	return r, nil
+}
+
+// Put records or replaces the entry for libraryPath and flushes to disk.
+func (r *Registry) Put(entry RegistryEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[entry.LibraryPath] = entry
+	return r.flushLocked()
+}
+
+// Get returns the entry recorded for libraryPath, if any.
+func (r *Registry) Get(libraryPath string) (RegistryEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.entries[libraryPath]
+	return e, ok
+}
+
+// Delete removes libraryPath from the registry and flushes to disk.
+func (r *Registry) Delete(libraryPath string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, libraryPath)
+	return r.flushLocked()
+}
+
+// List returns every entry currently known to the registry, in no
+// particular order.
+func (r *Registry) List() []RegistryEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]RegistryEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		out = append(out, e)
+	}
+	return out
+}
+
+func (r *Registry) flushLocked() error {
+	entries := make([]RegistryEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, e)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("manager: encode registry: %w", err)
+	}
+
+	if dir := filepath.Dir(r.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("manager: create registry dir %s: %w", dir, err)
+		}
+	}
+
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("manager: write registry %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, r.path)
+}
@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRegistryPutGetRoundTrip(t *testing.T) {
+	reg, err := NewRegistry(filepath.Join(t.TempDir(), "registry.json"))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	if err := reg.Put(RegistryEntry{LibraryPath: "/plugins/a.so", MountPoints: []string{"/mnt/a"}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	entry, ok := reg.Get("/plugins/a.so")
+	if !ok {
+		t.Fatal("Get: entry not found after Put")
+	}
+	if !reflect.DeepEqual(entry.MountPoints, []string{"/mnt/a"}) {
+		t.Fatalf("Get().MountPoints = %v, want [/mnt/a]", entry.MountPoints)
+	}
+
+	if _, ok := reg.Get("/plugins/unknown.so"); ok {
+		t.Fatal("Get: want false for a library path never Put")
+	}
+}
+
+func TestRegistryPutOverwritesWholeEntry(t *testing.T) {
+	reg, err := NewRegistry(filepath.Join(t.TempDir(), "registry.json"))
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	if err := reg.Put(RegistryEntry{LibraryPath: "/plugins/a.so", MountPoints: []string{"/mnt/a"}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	// Putting a zero-value entry for the same library path (what
+	// Manager.Load used to do unconditionally) clobbers MountPoints;
+	// callers that want to preserve it must read-modify-write via Get.
+	if err := reg.Put(RegistryEntry{LibraryPath: "/plugins/a.so"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	entry, _ := reg.Get("/plugins/a.so")
+	if len(entry.MountPoints) != 0 {
+		t.Fatalf("Get().MountPoints = %v, want empty after a zero-value Put", entry.MountPoints)
+	}
+}
+
+func TestRegistryPersistsAcrossReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.json")
+
+	reg, err := NewRegistry(path)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	want := RegistryEntry{
+		LibraryPath: "/plugins/a.so",
+		MountPoints: []string{"/mnt/a", "/mnt/b"},
+		Config:      map[string]interface{}{"k": "v"},
+	}
+	if err := reg.Put(want); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reloaded, err := NewRegistry(path)
+	if err != nil {
+		t.Fatalf("NewRegistry (reload): %v", err)
+	}
+	got, ok := reloaded.Get("/plugins/a.so")
+	if !ok {
+		t.Fatal("Get: entry missing after reload")
+	}
+	if !reflect.DeepEqual(got.MountPoints, want.MountPoints) {
+		t.Fatalf("reloaded MountPoints = %v, want %v", got.MountPoints, want.MountPoints)
+	}
+}
@@ -0,0 +1,254 @@
+package manager
+
+import (
+	"fmt"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+)
+
+// remotePlugin implements plugin.ServicePlugin by proxying every call to the
+// out-of-process host over its framed Unix-socket transport. Callers of
+// mountablefs (and, transitively, the AGFS_* C ABI) see no difference from
+// an in-process plugin.
+type remotePlugin struct {
+	manager     *Manager
+	libraryPath string
+	host        *host
+	name        string
+}
+
+func (p *remotePlugin) Name() string { return p.name }
+
+func (p *remotePlugin) ReadDir(path string) ([]filesystem.FileInfo, error) {
+	var out []filesystem.FileInfo
+	err := p.host.call("ReadDir", struct {
+		Path string `json:"path"`
+	}{path}, &out)
+	return out, err
+}
+
+func (p *remotePlugin) Read(path string, offset, size int64) ([]byte, error) {
+	var out []byte
+	err := p.host.call("Read", struct {
+		Path   string `json:"path"`
+		Offset int64  `json:"offset"`
+		Size   int64  `json:"size"`
+	}{path, offset, size}, &out)
+	return out, err
+}
+
+func (p *remotePlugin) Write(path string, data []byte, offset int64, flags filesystem.WriteFlag) (int, error) {
+	var n int
+	err := p.host.call("Write", struct {
+		Path   string             `json:"path"`
+		Data   []byte             `json:"data"`
+		Offset int64              `json:"offset"`
+		Flags  filesystem.WriteFlag `json:"flags"`
+	}{path, data, offset, flags}, &n)
+	return n, err
+}
+
+func (p *remotePlugin) Create(path string) error {
+	return p.host.call("Create", struct {
+		Path string `json:"path"`
+	}{path}, nil)
+}
+
+func (p *remotePlugin) Mkdir(path string, mode uint32) error {
+	return p.host.call("Mkdir", struct {
+		Path string `json:"path"`
+		Mode uint32 `json:"mode"`
+	}{path, mode}, nil)
+}
+
+func (p *remotePlugin) Remove(path string) error {
+	return p.host.call("Remove", struct {
+		Path string `json:"path"`
+	}{path}, nil)
+}
+
+func (p *remotePlugin) RemoveAll(path string) error {
+	return p.host.call("RemoveAll", struct {
+		Path string `json:"path"`
+	}{path}, nil)
+}
+
+func (p *remotePlugin) Stat(path string) (filesystem.FileInfo, error) {
+	var out filesystem.FileInfo
+	err := p.host.call("Stat", struct {
+		Path string `json:"path"`
+	}{path}, &out)
+	return out, err
+}
+
+func (p *remotePlugin) Rename(oldPath, newPath string) error {
+	return p.host.call("Rename", struct {
+		OldPath string `json:"old_path"`
+		NewPath string `json:"new_path"`
+	}{oldPath, newPath}, nil)
+}
+
+func (p *remotePlugin) Chmod(path string, mode uint32) error {
+	return p.host.call("Chmod", struct {
+		Path string `json:"path"`
+		Mode uint32 `json:"mode"`
+	}{path, mode}, nil)
+}
+
+func (p *remotePlugin) Touch(path string) error {
+	return p.host.call("Touch", struct {
+		Path string `json:"path"`
+	}{path}, nil)
+}
+
+func (p *remotePlugin) OpenHandle(path string, flags filesystem.OpenFlag, mode uint32) (filesystem.FileHandle, error) {
+	var hostID int64
+	err := p.host.call("Handle.Open", struct {
+		Path  string              `json:"path"`
+		Flags filesystem.OpenFlag `json:"flags"`
+		Mode  uint32              `json:"mode"`
+	}{path, flags, mode}, &hostID)
+	if err != nil {
+		return nil, fmt.Errorf("manager: open handle %s on %s: %w", path, p.libraryPath, err)
+	}
+
+	mgrID := p.manager.trackHandle(p.libraryPath, path, flags, mode, hostID)
+	return &remoteHandle{plugin: p, mgrID: mgrID, path: path, flags: flags}, nil
+}
+
+// remoteHandle implements filesystem.FileHandle by forwarding calls to the
+// host. mgrID is only ever used locally, to look up the current host-local
+// handle ID via Manager.currentHostID: that ID is what the host's
+// storeHandle actually assigned and is what changes across a crash replay,
+// so it must be re-resolved on every call rather than cached once.
+type remoteHandle struct {
+	plugin *remotePlugin
+	mgrID  int64
+	path   string
+	flags  filesystem.OpenFlag
+}
+
+func (h *remoteHandle) call(method string, args interface{}, result interface{}) error {
+	return h.plugin.host.call(method, args, result)
+}
+
+func (h *remoteHandle) hostID() (int64, error) {
+	id, ok := h.plugin.manager.currentHostID(h.mgrID)
+	if !ok {
+		return 0, fmt.Errorf("manager: handle %s is no longer tracked", h.path)
+	}
+	return id, nil
+}
+
+func (h *remoteHandle) Read(p []byte) (int, error) {
+	id, err := h.hostID()
+	if err != nil {
+		return 0, err
+	}
+	var out []byte
+	n := 0
+	err = h.call("Handle.Read", struct {
+		HandleID int64 `json:"handle_id"`
+		Size     int   `json:"size"`
+	}{id, len(p)}, &out)
+	if err == nil {
+		n = copy(p, out)
+	}
+	return n, err
+}
+
+func (h *remoteHandle) ReadAt(p []byte, offset int64) (int, error) {
+	id, err := h.hostID()
+	if err != nil {
+		return 0, err
+	}
+	var out []byte
+	n := 0
+	err = h.call("Handle.ReadAt", struct {
+		HandleID int64 `json:"handle_id"`
+		Size     int   `json:"size"`
+		Offset   int64 `json:"offset"`
+	}{id, len(p), offset}, &out)
+	if err == nil {
+		n = copy(p, out)
+	}
+	return n, err
+}
+
+func (h *remoteHandle) Write(p []byte) (int, error) {
+	id, err := h.hostID()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = h.call("Handle.Write", struct {
+		HandleID int64  `json:"handle_id"`
+		Data     []byte `json:"data"`
+	}{id, p}, &n)
+	return n, err
+}
+
+func (h *remoteHandle) WriteAt(p []byte, offset int64) (int, error) {
+	id, err := h.hostID()
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = h.call("Handle.WriteAt", struct {
+		HandleID int64  `json:"handle_id"`
+		Data     []byte `json:"data"`
+		Offset   int64  `json:"offset"`
+	}{id, p, offset}, &n)
+	return n, err
+}
+
+func (h *remoteHandle) Seek(offset int64, whence int) (int64, error) {
+	id, err := h.hostID()
+	if err != nil {
+		return 0, err
+	}
+	var pos int64
+	err = h.call("Handle.Seek", struct {
+		HandleID int64 `json:"handle_id"`
+		Offset   int64 `json:"offset"`
+		Whence   int   `json:"whence"`
+	}{id, offset, whence}, &pos)
+	return pos, err
+}
+
+func (h *remoteHandle) Sync() error {
+	id, err := h.hostID()
+	if err != nil {
+		return err
+	}
+	return h.call("Handle.Sync", struct {
+		HandleID int64 `json:"handle_id"`
+	}{id}, nil)
+}
+
+func (h *remoteHandle) Stat() (filesystem.FileInfo, error) {
+	id, err := h.hostID()
+	if err != nil {
+		return filesystem.FileInfo{}, err
+	}
+	var out filesystem.FileInfo
+	err = h.call("Handle.Stat", struct {
+		HandleID int64 `json:"handle_id"`
+	}{id}, &out)
+	return out, err
+}
+
+func (h *remoteHandle) Close() error {
+	defer h.plugin.manager.untrackHandle(h.mgrID)
+	id, err := h.hostID()
+	if err != nil {
+		return err
+	}
+	return h.call("Handle.Close", struct {
+		HandleID int64 `json:"handle_id"`
+	}{id}, nil)
+}
+
+func (h *remoteHandle) Path() string { return h.path }
+
+func (h *remoteHandle) Flags() filesystem.OpenFlag { return h.flags }
@@ -0,0 +1,20 @@
+package manager
+
+import "encoding/json"
+
+// Request is a single call sent from the manager to a plugin host over the
+// framed Unix-socket transport. Method mirrors the filesystem.FileSystem /
+// filesystem.FileHandle method being invoked, e.g. "ReadDir" or
+// "Handle.WriteAt".
+type Request struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Args   json.RawMessage `json:"args"`
+}
+
+// Response is the host's reply to a Request with the same ID.
+type Response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
@@ -0,0 +1,44 @@
+package manager
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxFrameSize guards against a corrupt or malicious length prefix turning
+// into a multi-gigabyte allocation.
+const maxFrameSize = 64 << 20 // 64 MiB
+
+// WriteFrame writes payload to w prefixed with its length as a big-endian
+// uint32, the framing used by the manager<->host Unix-socket transport.
+func WriteFrame(w io.Writer, payload []byte) error {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return fmt.Errorf("manager: write frame header: %w", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		return fmt.Errorf("manager: write frame body: %w", err)
+	}
+	return nil
+}
+
+// ReadFrame reads one length-prefixed frame written by WriteFrame.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("manager: frame size %d exceeds limit %d", size, maxFrameSize)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("manager: read frame body: %w", err)
+	}
+	return payload, nil
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestWriteReadFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	payload := []byte(`{"id":1,"method":"ReadDir"}`)
+
+	if err := WriteFrame(&buf, payload); err != nil {
+		t.Fatalf("WriteFrame: %v", err)
+	}
+
+	got, err := ReadFrame(&buf)
+	if err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("ReadFrame = %q, want %q", got, payload)
+	}
+}
+
+func TestReadFrameRejectsOversizedLength(t *testing.T) {
+	// A length prefix alone, well past maxFrameSize, with no body.
+	buf := bytes.NewBuffer([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+
+	if _, err := ReadFrame(buf); err == nil {
+		t.Fatal("ReadFrame: want error for oversized frame length, got nil")
+	}
+}
+
+func TestReadFrameEOF(t *testing.T) {
+	if _, err := ReadFrame(bytes.NewReader(nil)); err != io.EOF {
+		t.Fatalf("ReadFrame on empty reader = %v, want io.EOF", err)
+	}
+}
+
+func TestIsConnError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"plain EOF", io.EOF, true},
+		{"wrapped EOF", fmt.Errorf("manager: read frame body: %w", io.EOF), true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"net.OpError", &net.OpError{Op: "read", Err: errors.New("broken pipe")}, true},
+		{"wrapped net.OpError", fmt.Errorf("manager: write frame header: %w", &net.OpError{Op: "write", Err: errors.New("epipe")}), true},
+		{"unrelated error", errors.New("unknown method"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isConnError(tc.err); got != tc.want {
+				t.Errorf("isConnError(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
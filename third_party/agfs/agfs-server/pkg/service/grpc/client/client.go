@@ -0,0 +1,215 @@
+// Package client is a Go client for pkg/service/grpc that implements
+// filesystem.FileSystem, so a remote openviking instance can be mounted
+// like any other plugin (e.g. via mountablefs.MountableFS.RegisterPluginFactory).
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+	openvikingpb "github.com/c4pt0r/agfs/agfs-server/pkg/service/grpc/openvikingpb"
+)
+
+// Client implements filesystem.FileSystem by calling a remote
+// openviking-server over gRPC.
+type Client struct {
+	conn *grpc.ClientConn
+	rpc  openvikingpb.OpenvikingServiceClient
+}
+
+// Dial connects to an openviking-server listening at target, which may be
+// a "host:port" TCP address or a "unix:///path/to.sock" address.
+func Dial(target string) (*Client, error) {
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("client: dial %s: %w", target, err)
+	}
+	return &Client{conn: conn, rpc: openvikingpb.NewOpenvikingServiceClient(conn)}, nil
+}
+
+// Close tears down the underlying gRPC connection.
+func (c *Client) Close() error { return c.conn.Close() }
+
+func (c *Client) ReadDir(path string) ([]filesystem.FileInfo, error) {
+	resp, err := c.rpc.Ls(context.Background(), &openvikingpb.LsRequest{Path: path})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]filesystem.FileInfo, len(resp.Files))
+	for i, f := range resp.Files {
+		out[i] = fileInfoFromPB(f)
+	}
+	return out, nil
+}
+
+func (c *Client) Read(path string, offset, size int64) ([]byte, error) {
+	resp, err := c.rpc.Read(context.Background(), &openvikingpb.ReadRequest{Path: path, Offset: offset, Size: size})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (c *Client) Write(path string, data []byte, offset int64, flags filesystem.WriteFlag) (int, error) {
+	resp, err := c.rpc.Write(context.Background(), &openvikingpb.WriteRequest{
+		Path: path, Data: data, Offset: offset, Flags: uint32(flags),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(resp.BytesWritten), nil
+}
+
+func (c *Client) Stat(path string) (filesystem.FileInfo, error) {
+	resp, err := c.rpc.Stat(context.Background(), &openvikingpb.StatRequest{Path: path})
+	if err != nil {
+		return filesystem.FileInfo{}, err
+	}
+	return fileInfoFromPB(resp.Info), nil
+}
+
+func (c *Client) Mount(fstype, path string, config map[string]interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("client: encode mount config: %w", err)
+	}
+	_, err = c.rpc.Mount(context.Background(), &openvikingpb.MountRequest{Fstype: fstype, Path: path, ConfigJson: string(data)})
+	return err
+}
+
+func (c *Client) Unmount(path string) error {
+	_, err := c.rpc.Unmount(context.Background(), &openvikingpb.UnmountRequest{Path: path})
+	return err
+}
+
+func (c *Client) OpenHandle(path string, flags filesystem.OpenFlag, mode uint32) (filesystem.FileHandle, error) {
+	resp, err := c.rpc.OpenHandle(context.Background(), &openvikingpb.OpenHandleRequest{
+		Path: path, Flags: int32(flags), Mode: mode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &remoteHandle{client: c, id: resp.HandleId, path: path, flags: flags}, nil
+}
+
+func fileInfoFromPB(f *openvikingpb.FileInfo) filesystem.FileInfo {
+	modTime, _ := time.Parse(time.RFC3339Nano, f.ModTime)
+	return filesystem.FileInfo{
+		Name:    f.Name,
+		Size:    f.Size,
+		Mode:    f.Mode,
+		ModTime: modTime,
+		IsDir:   f.IsDir,
+	}
+}
+
+// remoteHandle implements filesystem.FileHandle against a handle opened on
+// the server via Client.OpenHandle. Reads are serviced by the streaming
+// HandleRead RPC so large transfers don't require one giant message.
+type remoteHandle struct {
+	client *Client
+	id     int64
+	path   string
+	flags  filesystem.OpenFlag
+	pos    int64
+}
+
+func (h *remoteHandle) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		// Size 0 would ask the server to stream to EOF.
+		return 0, nil
+	}
+	// Size bounds the server to exactly len(p) bytes, so this Recv loop
+	// always drains the stream to its natural io.EOF rather than abandoning
+	// it early — the server never has more queued up than we asked for.
+	stream, err := h.client.rpc.HandleRead(context.Background(), &openvikingpb.HandleReadRequest{
+		HandleId: h.id, ChunkSize: int64(len(p)), Size: int64(len(p)),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+		total += copy(p[total:], chunk.Data)
+	}
+	h.pos += int64(total)
+	if total == 0 {
+		return 0, io.EOF
+	}
+	return total, nil
+}
+
+func (h *remoteHandle) ReadAt(p []byte, offset int64) (int, error) {
+	if _, err := h.Seek(offset, 0); err != nil {
+		return 0, err
+	}
+	return h.Read(p)
+}
+
+func (h *remoteHandle) Write(p []byte) (int, error) {
+	stream, err := h.client.rpc.HandleWrite(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	if err := stream.Send(&openvikingpb.HandleWriteChunk{HandleId: h.id, Data: p}); err != nil {
+		return 0, err
+	}
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		return 0, err
+	}
+	h.pos += summary.BytesWritten
+	return int(summary.BytesWritten), nil
+}
+
+func (h *remoteHandle) WriteAt(p []byte, offset int64) (int, error) {
+	if _, err := h.Seek(offset, 0); err != nil {
+		return 0, err
+	}
+	return h.Write(p)
+}
+
+func (h *remoteHandle) Seek(offset int64, whence int) (int64, error) {
+	resp, err := h.client.rpc.HandleSeek(context.Background(), &openvikingpb.HandleSeekRequest{
+		HandleId: h.id, Offset: offset, Whence: int32(whence),
+	})
+	if err != nil {
+		return 0, err
+	}
+	h.pos = resp.Position
+	return resp.Position, nil
+}
+
+func (h *remoteHandle) Sync() error {
+	_, err := h.client.rpc.HandleSync(context.Background(), &openvikingpb.HandleSyncRequest{HandleId: h.id})
+	return err
+}
+
+func (h *remoteHandle) Stat() (filesystem.FileInfo, error) {
+	return h.client.Stat(h.path)
+}
+
+func (h *remoteHandle) Close() error {
+	_, err := h.client.rpc.CloseHandle(context.Background(), &openvikingpb.CloseHandleRequest{HandleId: h.id})
+	return err
+}
+
+func (h *remoteHandle) Path() string { return h.path }
+
+func (h *remoteHandle) Flags() filesystem.OpenFlag { return h.flags }
@@ -0,0 +1,58 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+)
+
+// handleTable assigns int64 IDs to open filesystem.FileHandles, the same
+// scheme cmd/pybinding uses for the C ABI.
+type handleTable struct {
+	mu   sync.RWMutex
+	next int64
+	byID map[int64]filesystem.FileHandle
+}
+
+func newHandleTable() *handleTable {
+	return &handleTable{byID: make(map[int64]filesystem.FileHandle)}
+}
+
+func (t *handleTable) store(h filesystem.FileHandle) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next++
+	id := t.next
+	t.byID[id] = h
+	return id
+}
+
+func (t *handleTable) get(id int64) (filesystem.FileHandle, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	h, ok := t.byID[id]
+	return h, ok
+}
+
+func (t *handleTable) take(id int64) (filesystem.FileHandle, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.byID[id]
+	if ok {
+		delete(t.byID, id)
+	}
+	return h, ok
+}
+
+func decodeConfig(raw string) (map[string]interface{}, error) {
+	if raw == "" {
+		return map[string]interface{}{}, nil
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return nil, fmt.Errorf("grpc: decode config_json: %w", err)
+	}
+	return config, nil
+}
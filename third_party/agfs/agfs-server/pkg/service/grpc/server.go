@@ -0,0 +1,254 @@
+// Package grpc exposes the same operations as the AGFS_* C ABI (Ls, Read,
+// Write, Stat, Mount, Unmount, handle-based I/O, plugin management) as a
+// gRPC service, so openviking can be driven from non-cgo languages and
+// across machines. The wire types it uses (package openvikingpb) are
+// generated from openviking.proto via `make proto`; see that file for the
+// source of truth.
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/c4pt0r/agfs/agfs-server/pkg/filesystem"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/mountablefs"
+	"github.com/c4pt0r/agfs/agfs-server/pkg/plugin/configschema"
+	openvikingpb "github.com/c4pt0r/agfs/agfs-server/pkg/service/grpc/openvikingpb"
+)
+
+// defaultHandleChunkSize is used for HandleRead when the caller doesn't
+// specify one.
+const defaultHandleChunkSize = 1 << 20 // 1 MiB
+
+// Server implements openvikingpb.OpenvikingServiceServer against an
+// in-process mountablefs.MountableFS, the same one the CGo bindings drive.
+type Server struct {
+	openvikingpb.UnimplementedOpenvikingServiceServer
+
+	fs *mountablefs.MountableFS
+
+	handles *handleTable
+}
+
+// NewServer wraps fs for gRPC access.
+func NewServer(fs *mountablefs.MountableFS) *Server {
+	return &Server{fs: fs, handles: newHandleTable()}
+}
+
+func (s *Server) Ls(ctx context.Context, req *openvikingpb.LsRequest) (*openvikingpb.LsResponse, error) {
+	files, err := s.fs.ReadDir(req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*openvikingpb.FileInfo, len(files))
+	for i, f := range files {
+		out[i] = &openvikingpb.FileInfo{
+			Name:    f.Name,
+			Size:    f.Size,
+			Mode:    f.Mode,
+			ModTime: f.ModTime.Format(time.RFC3339Nano),
+			IsDir:   f.IsDir,
+		}
+	}
+	return &openvikingpb.LsResponse{Files: out}, nil
+}
+
+func (s *Server) Read(ctx context.Context, req *openvikingpb.ReadRequest) (*openvikingpb.ReadResponse, error) {
+	data, err := s.fs.Read(req.Path, req.Offset, req.Size)
+	if err != nil && err.Error() != "EOF" {
+		return nil, err
+	}
+	return &openvikingpb.ReadResponse{Data: data}, nil
+}
+
+func (s *Server) Write(ctx context.Context, req *openvikingpb.WriteRequest) (*openvikingpb.WriteResponse, error) {
+	n, err := s.fs.Write(req.Path, req.Data, req.Offset, filesystem.WriteFlag(req.Flags))
+	if err != nil {
+		return nil, err
+	}
+	return &openvikingpb.WriteResponse{BytesWritten: int64(n)}, nil
+}
+
+func (s *Server) Stat(ctx context.Context, req *openvikingpb.StatRequest) (*openvikingpb.StatResponse, error) {
+	info, err := s.fs.Stat(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &openvikingpb.StatResponse{Info: &openvikingpb.FileInfo{
+		Name:    info.Name,
+		Size:    info.Size,
+		Mode:    info.Mode,
+		ModTime: info.ModTime.Format(time.RFC3339Nano),
+		IsDir:   info.IsDir,
+	}}, nil
+}
+
+func (s *Server) Mount(ctx context.Context, req *openvikingpb.MountRequest) (*openvikingpb.MountResponse, error) {
+	config, err := decodeConfig(req.ConfigJson)
+	if err != nil {
+		return nil, err
+	}
+	config, err = negotiateMountConfig(s.fs, req.Fstype, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fs.MountPlugin(req.Fstype, req.Path, config); err != nil {
+		return nil, err
+	}
+	return &openvikingpb.MountResponse{}, nil
+}
+
+// negotiateMountConfig mirrors cmd/pybinding's helper of the same name so
+// gRPC mounts can't bypass the config-version/capability checks the C ABI
+// enforces: it runs config through configschema.Negotiate against a fresh
+// instance of fsType, upgrading it to the plugin's newest declared config
+// version and checking any "required_capabilities" it lists. Plugins that
+// don't implement configschema.VersionedPlugin opt out silently, same as
+// cmd/pybinding's copy.
+func negotiateMountConfig(fs *mountablefs.MountableFS, fsType string, config map[string]interface{}) (map[string]interface{}, error) {
+	inst, err := fs.NewPluginInstance(fsType)
+	if err != nil {
+		// Let the real mount attempt below surface the "unknown fstype"
+		// error; duplicating it here would just produce two slightly
+		// different messages for the same failure.
+		return config, nil
+	}
+
+	vp, ok := inst.(configschema.VersionedPlugin)
+	if !ok {
+		return config, nil
+	}
+
+	return configschema.Negotiate(vp, config)
+}
+
+func (s *Server) Unmount(ctx context.Context, req *openvikingpb.UnmountRequest) (*openvikingpb.UnmountResponse, error) {
+	if err := s.fs.Unmount(req.Path); err != nil {
+		return nil, err
+	}
+	return &openvikingpb.UnmountResponse{}, nil
+}
+
+func (s *Server) ListPlugins(ctx context.Context, req *openvikingpb.ListPluginsRequest) (*openvikingpb.ListPluginsResponse, error) {
+	return &openvikingpb.ListPluginsResponse{LoadedPlugins: s.fs.GetLoadedExternalPlugins()}, nil
+}
+
+func (s *Server) LoadPlugin(ctx context.Context, req *openvikingpb.LoadPluginRequest) (*openvikingpb.LoadPluginResponse, error) {
+	p, err := s.fs.LoadExternalPlugin(req.LibraryPath)
+	if err != nil {
+		return nil, err
+	}
+	return &openvikingpb.LoadPluginResponse{Name: p.Name()}, nil
+}
+
+func (s *Server) OpenHandle(ctx context.Context, req *openvikingpb.OpenHandleRequest) (*openvikingpb.OpenHandleResponse, error) {
+	handle, err := s.fs.OpenHandle(req.Path, filesystem.OpenFlag(req.Flags), req.Mode)
+	if err != nil {
+		return nil, err
+	}
+	return &openvikingpb.OpenHandleResponse{HandleId: s.handles.store(handle)}, nil
+}
+
+func (s *Server) CloseHandle(ctx context.Context, req *openvikingpb.CloseHandleRequest) (*openvikingpb.CloseHandleResponse, error) {
+	handle, ok := s.handles.take(req.HandleId)
+	if !ok {
+		return nil, fmt.Errorf("grpc: unknown handle %d", req.HandleId)
+	}
+	if err := handle.Close(); err != nil {
+		return nil, err
+	}
+	return &openvikingpb.CloseHandleResponse{}, nil
+}
+
+func (s *Server) HandleSeek(ctx context.Context, req *openvikingpb.HandleSeekRequest) (*openvikingpb.HandleSeekResponse, error) {
+	handle, ok := s.handles.get(req.HandleId)
+	if !ok {
+		return nil, fmt.Errorf("grpc: unknown handle %d", req.HandleId)
+	}
+	pos, err := handle.Seek(req.Offset, int(req.Whence))
+	if err != nil {
+		return nil, err
+	}
+	return &openvikingpb.HandleSeekResponse{Position: pos}, nil
+}
+
+func (s *Server) HandleSync(ctx context.Context, req *openvikingpb.HandleSyncRequest) (*openvikingpb.HandleSyncResponse, error) {
+	handle, ok := s.handles.get(req.HandleId)
+	if !ok {
+		return nil, fmt.Errorf("grpc: unknown handle %d", req.HandleId)
+	}
+	if err := handle.Sync(); err != nil {
+		return nil, err
+	}
+	return &openvikingpb.HandleSyncResponse{}, nil
+}
+
+// HandleRead streams a handle's contents in chunk_size pieces so a large
+// file never has to fit in a single gRPC message. It stops once req.Size
+// bytes have been sent (or the handle hits EOF first) rather than
+// streaming to the end of the file, so the handle's read position only
+// ever advances by exactly what the caller asked for. req.Size <= 0 keeps
+// the old "stream to EOF" behavior, for callers that predate the field.
+func (s *Server) HandleRead(req *openvikingpb.HandleReadRequest, stream openvikingpb.OpenvikingService_HandleReadServer) error {
+	handle, ok := s.handles.get(req.HandleId)
+	if !ok {
+		return fmt.Errorf("grpc: unknown handle %d", req.HandleId)
+	}
+
+	chunkSize := req.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultHandleChunkSize
+	}
+
+	buf := make([]byte, chunkSize)
+	var sent int64
+	for req.Size <= 0 || sent < req.Size {
+		if req.Size > 0 && int64(len(buf)) > req.Size-sent {
+			buf = buf[:req.Size-sent]
+		}
+		n, err := handle.Read(buf)
+		if n > 0 {
+			if serr := stream.Send(&openvikingpb.HandleReadChunk{Data: append([]byte(nil), buf[:n]...)}); serr != nil {
+				return serr
+			}
+			sent += int64(n)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleWrite accepts a stream of chunks addressed to (possibly different)
+// open handles and writes each one through in arrival order before
+// returning the total bytes written.
+func (s *Server) HandleWrite(stream openvikingpb.OpenvikingService_HandleWriteServer) error {
+	var total int64
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&openvikingpb.HandleWriteSummary{BytesWritten: total})
+		}
+		if err != nil {
+			return err
+		}
+
+		handle, ok := s.handles.get(chunk.HandleId)
+		if !ok {
+			return fmt.Errorf("grpc: unknown handle %d", chunk.HandleId)
+		}
+
+		n, err := handle.Write(chunk.Data)
+		if err != nil {
+			return err
+		}
+		total += int64(n)
+	}
+}